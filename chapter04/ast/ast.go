@@ -0,0 +1,328 @@
+// Package ast declares the syntax tree of Simple. Every node has the position where it starts, which maps to a line and column through the token.File of its source.
+package ast
+
+import "go/token"
+
+// Node is any node of the syntax tree.
+type Node interface {
+	// Pos returns the position of the first character of the node
+	Pos() token.Pos
+}
+
+// Expr is an expression, which has a value.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is a statement. Declarations are statements, since they can appear in any block.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// TypeExpr is the name of a type in a declaration: a primitive or struct name, an array type, or a nullable reference type.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// LShr is the operator of the logical shift right >>>. go/token has no such token, so the token of &^, which Simple does not have, stands in for it.
+const LShr = token.AND_NOT
+
+// Expressions
+
+type (
+	// Ident is a name, which may also be true, false or the name of a type.
+	Ident struct {
+		NamePos token.Pos
+		Name    string
+	}
+
+	// BasicLit is an int, a flt or a string literal, written as in the source.
+	BasicLit struct {
+		ValuePos token.Pos
+		// Kind is token.INT, token.FLOAT or token.STRING
+		Kind  token.Token
+		Value string
+	}
+
+	UnaryExpr struct {
+		OpPos token.Pos
+		Op    token.Token
+		X     Expr
+	}
+
+	BinaryExpr struct {
+		X     Expr
+		OpPos token.Pos
+		Op    token.Token
+		Y     Expr
+	}
+
+	ParenExpr struct {
+		Lparen token.Pos
+		X      Expr
+		Rparen token.Pos
+	}
+
+	// CallExpr is a call of a function, or a conversion when Fun is int or flt.
+	CallExpr struct {
+		Fun    *Ident
+		Lparen token.Pos
+		Args   []Expr
+		Rparen token.Pos
+	}
+
+	// NewExpr allocates a struct, or an array of Type when Len is not nil.
+	NewExpr struct {
+		New  token.Pos
+		Type *Ident
+		Len  Expr
+	}
+
+	// FieldExpr is the access to a field of a struct: X.Field.
+	FieldExpr struct {
+		X     Expr
+		Field *Ident
+	}
+
+	// LenExpr is the length of an array: X#.
+	LenExpr struct {
+		X    Expr
+		Hash token.Pos
+	}
+
+	// IndexExpr is the access to an element of an array: X[Index].
+	IndexExpr struct {
+		X      Expr
+		Lbrack token.Pos
+		Index  Expr
+		Rbrack token.Pos
+	}
+
+	// CondExpr is the conditional expression Cond ? X : Y, which only evaluates one of X and Y.
+	CondExpr struct {
+		Cond     Expr
+		Question token.Pos
+		X        Expr
+		Colon    token.Pos
+		Y        Expr
+	}
+)
+
+func (x *Ident) Pos() token.Pos      { return x.NamePos }
+func (x *BasicLit) Pos() token.Pos   { return x.ValuePos }
+func (x *UnaryExpr) Pos() token.Pos  { return x.OpPos }
+func (x *BinaryExpr) Pos() token.Pos { return x.X.Pos() }
+func (x *ParenExpr) Pos() token.Pos  { return x.Lparen }
+func (x *CallExpr) Pos() token.Pos   { return x.Fun.Pos() }
+func (x *NewExpr) Pos() token.Pos    { return x.New }
+func (x *FieldExpr) Pos() token.Pos  { return x.X.Pos() }
+func (x *LenExpr) Pos() token.Pos    { return x.X.Pos() }
+func (x *IndexExpr) Pos() token.Pos  { return x.X.Pos() }
+func (x *CondExpr) Pos() token.Pos   { return x.Cond.Pos() }
+
+func (*Ident) exprNode()      {}
+func (*BasicLit) exprNode()   {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+func (*ParenExpr) exprNode()  {}
+func (*CallExpr) exprNode()   {}
+func (*NewExpr) exprNode()    {}
+func (*FieldExpr) exprNode()  {}
+func (*LenExpr) exprNode()    {}
+func (*IndexExpr) exprNode()  {}
+func (*CondExpr) exprNode()   {}
+
+// Types
+
+// ArrayType is the type of an array of Elt: Elt[].
+type ArrayType struct {
+	Elt    *Ident
+	Lbrack token.Pos
+}
+
+func (t *ArrayType) Pos() token.Pos { return t.Elt.Pos() }
+
+// NullableType is the type of a reference that may be null: X?.
+type NullableType struct {
+	X        TypeExpr
+	Question token.Pos
+}
+
+func (t *NullableType) Pos() token.Pos { return t.X.Pos() }
+
+func (*Ident) typeNode()        {}
+func (*ArrayType) typeNode()    {}
+func (*NullableType) typeNode() {}
+
+// Statements
+
+type (
+	ReturnStmt struct {
+		Return token.Pos
+		Result Expr
+	}
+
+	BlockStmt struct {
+		Lbrace token.Pos
+		List   []Stmt
+		Rbrace token.Pos
+	}
+
+	// VarDecl declares a name: a typed declaration (int x = 1;), an inferred one (var x = 1;) or a final one (val x = 1;).
+	VarDecl struct {
+		// Decl is the position of the type, or of the var or val keyword
+		Decl token.Pos
+		// Type is nil when the type is inferred from the value
+		Type TypeExpr
+		Name *Ident
+		// Value is nil when the declaration has no value
+		Value Expr
+		// Final is true for val, which cannot be assigned again
+		Final bool
+	}
+
+	// FuncDecl declares a function: Result Name(Params) Body.
+	FuncDecl struct {
+		// Export is the position of the export keyword, or token.NoPos if the function is not exported
+		Export token.Pos
+		Result TypeExpr
+		Name   *Ident
+		Params []*Field
+		Body   *BlockStmt
+	}
+
+	// StructDecl declares a struct: struct Name { Fields }.
+	StructDecl struct {
+		// Export is the position of the export keyword, or token.NoPos if the struct is not exported
+		Export token.Pos
+		Struct token.Pos
+		Name   *Ident
+		Fields []*Field
+	}
+
+	// ImportDecl imports the exported functions and structs of a module: import "Path";.
+	ImportDecl struct {
+		Import token.Pos
+		// Path is the string literal naming the module
+		Path *BasicLit
+	}
+
+	// AssignStmt assigns to a name, a field or an element. Tok is token.ASSIGN or a compound assignment such as token.ADD_ASSIGN.
+	AssignStmt struct {
+		Lhs    Expr
+		TokPos token.Pos
+		Tok    token.Token
+		Rhs    Expr
+	}
+
+	// IncDecStmt is X++ or X--. Tok is token.INC or token.DEC.
+	IncDecStmt struct {
+		X      Expr
+		TokPos token.Pos
+		Tok    token.Token
+	}
+
+	IfStmt struct {
+		If   token.Pos
+		Cond Expr
+		Body *BlockStmt
+		// Else is nil, a block or another if
+		Else Stmt
+	}
+
+	// ForStmt is a loop. A while loop only has a condition, and a missing condition is always true.
+	ForStmt struct {
+		For  token.Pos
+		Init Stmt
+		Cond Expr
+		Post Stmt
+		Body *BlockStmt
+	}
+
+	// BranchStmt is break or continue. Tok is token.BREAK or token.CONTINUE.
+	BranchStmt struct {
+		TokPos token.Pos
+		Tok    token.Token
+	}
+
+	// SwitchStmt selects the case whose value equals Tag. Cases do not fall through, and a break leaves the switch.
+	SwitchStmt struct {
+		Switch token.Pos
+		Tag    Expr
+		Cases  []*CaseClause
+		Rbrace token.Pos
+	}
+
+	// InstructionStmt is an instruction to the compiler: #Name.
+	InstructionStmt struct {
+		Hash token.Pos
+		Name *Ident
+	}
+)
+
+// CaseClause is a case of a switch: case List: Body. List is nil for the default case.
+type CaseClause struct {
+	Case  token.Pos
+	List  []Expr
+	Colon token.Pos
+	// Body starts at the colon, so names defined in a case are local to it
+	Body *BlockStmt
+}
+
+func (c *CaseClause) Pos() token.Pos { return c.Case }
+
+// Field is a parameter of a function or a field of a struct.
+type Field struct {
+	Type *Ident
+	Name *Ident
+}
+
+func (f *Field) Pos() token.Pos { return f.Type.Pos() }
+
+func (s *ReturnStmt) Pos() token.Pos      { return s.Return }
+func (s *BlockStmt) Pos() token.Pos       { return s.Lbrace }
+func (s *VarDecl) Pos() token.Pos         { return s.Decl }
+func (s *FuncDecl) Pos() token.Pos        { return s.Result.Pos() }
+func (s *StructDecl) Pos() token.Pos      { return s.Struct }
+func (s *ImportDecl) Pos() token.Pos      { return s.Import }
+func (s *AssignStmt) Pos() token.Pos      { return s.Lhs.Pos() }
+func (s *IncDecStmt) Pos() token.Pos      { return s.X.Pos() }
+func (s *IfStmt) Pos() token.Pos          { return s.If }
+func (s *ForStmt) Pos() token.Pos         { return s.For }
+func (s *BranchStmt) Pos() token.Pos      { return s.TokPos }
+func (s *SwitchStmt) Pos() token.Pos      { return s.Switch }
+func (s *InstructionStmt) Pos() token.Pos { return s.Hash }
+
+func (*ReturnStmt) stmtNode()      {}
+func (*BlockStmt) stmtNode()       {}
+func (*VarDecl) stmtNode()         {}
+func (*FuncDecl) stmtNode()        {}
+func (*StructDecl) stmtNode()      {}
+func (*ImportDecl) stmtNode()      {}
+func (*AssignStmt) stmtNode()      {}
+func (*IncDecStmt) stmtNode()      {}
+func (*IfStmt) stmtNode()          {}
+func (*ForStmt) stmtNode()         {}
+func (*BranchStmt) stmtNode()      {}
+func (*SwitchStmt) stmtNode()      {}
+func (*InstructionStmt) stmtNode() {}
+
+// Comments
+
+// Comment is a // or /* */ comment. Text includes the comment markers.
+type Comment struct {
+	Slash token.Pos
+	Text  string
+}
+
+func (c *Comment) Pos() token.Pos { return c.Slash }
+
+// CommentGroup is a sequence of comments on adjacent lines, with no code between them.
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) Pos() token.Pos { return g.List[0].Pos() }
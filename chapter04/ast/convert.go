@@ -0,0 +1,355 @@
+package ast
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+)
+
+// ConvertError is an error at the position of a go/ast node that has no equivalent in Simple.
+type ConvertError struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e *ConvertError) Error() string {
+	return "Syntax error: " + e.Msg
+}
+
+func convertError(pos token.Pos, msgFormat string, args ...any) *ConvertError {
+	return &ConvertError{Pos: pos, Msg: fmt.Sprintf(msgFormat, args...)}
+}
+
+// Arg is the name of the argument of a program.
+const Arg = "arg"
+
+// FromGoExpr converts a go/ast expression into a program that returns it.
+func FromGoExpr(e goast.Expr) (*BlockStmt, error) {
+	x, err := fromGoExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStmt{Lbrace: e.Pos(), List: []Stmt{&ReturnStmt{Return: e.Pos(), Result: x}}}, nil
+}
+
+// FromGoFunc converts a go/ast function such as func f(arg int) int { ... } into a program and the type of its result. The parameter is the argument of the program, and the function may return int, float64 or bool. The subset of Go that maps onto Simple is supported: :=, var, const, assignments, return, blocks, if and for. Positions are kept, so they map into the Go source.
+func FromGoFunc(fun *goast.FuncDecl) (*BlockStmt, *Ident, error) {
+	if fun.Recv != nil || fun.Type.TypeParams != nil {
+		return nil, nil, convertError(fun.Pos(), "expected a plain function")
+	}
+	results := fun.Type.Results
+	if results == nil || len(results.List) != 1 || len(results.List[0].Names) > 0 {
+		return nil, nil, convertError(fun.Type.Pos(), "expected a single unnamed result")
+	}
+	result, err := fromGoType(results.List[0].Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fun.Body == nil {
+		return nil, nil, convertError(fun.Pos(), "expected a function body")
+	}
+
+	body := &BlockStmt{Lbrace: fun.Body.Lbrace, Rbrace: fun.Body.Rbrace}
+	params := fun.Type.Params.List
+	if len(params) > 1 || (len(params) == 1 && len(params[0].Names) > 1) {
+		return nil, nil, convertError(fun.Type.Params.Pos(), "expected at most one parameter")
+	}
+	if len(params) == 1 {
+		typ, ok := params[0].Type.(*goast.Ident)
+		if !ok || typ.Name != "int" {
+			return nil, nil, convertError(params[0].Type.Pos(), "expected an int parameter")
+		}
+		// The parameter is the argument of the program, under the name of the parameter
+		if len(params[0].Names) == 1 && params[0].Names[0].Name != Arg && params[0].Names[0].Name != "_" {
+			name := fromGoIdent(params[0].Names[0])
+			arg := &Ident{NamePos: name.NamePos, Name: Arg}
+			body.List = append(body.List, &VarDecl{Decl: typ.Pos(), Type: fromGoIdent(typ), Name: name, Value: arg})
+		}
+	}
+
+	block, err := fromGoBlock(fun.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	body.List = append(body.List, block.List...)
+	return body, result, nil
+}
+
+func fromGoIdent(id *goast.Ident) *Ident {
+	return &Ident{NamePos: id.NamePos, Name: id.Name}
+}
+
+// fromGoType converts the name of a Go type into the name of the Simple type.
+func fromGoType(typ goast.Expr) (*Ident, error) {
+	id, ok := typ.(*goast.Ident)
+	if ok {
+		switch id.Name {
+		case "int", "bool":
+			return fromGoIdent(id), nil
+		case "float64":
+			return &Ident{NamePos: id.NamePos, Name: "flt"}, nil
+		}
+	}
+	return nil, convertError(typ.Pos(), "unsupported type, expected int, float64 or bool")
+}
+
+func fromGoBlock(b *goast.BlockStmt) (*BlockStmt, error) {
+	block := &BlockStmt{Lbrace: b.Lbrace, Rbrace: b.Rbrace}
+	for _, s := range b.List {
+		stmt, err := fromGoStmt(s)
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			block.List = append(block.List, stmt)
+		}
+	}
+	return block, nil
+}
+
+func fromGoStmt(s goast.Stmt) (Stmt, error) {
+	switch s := s.(type) {
+	case *goast.BlockStmt:
+		return fromGoBlock(s)
+	case *goast.ReturnStmt:
+		if len(s.Results) != 1 {
+			return nil, convertError(s.Pos(), "expected a single return value")
+		}
+		x, err := fromGoExpr(s.Results[0])
+		if err != nil {
+			return nil, err
+		}
+		return &ReturnStmt{Return: s.Return, Result: x}, nil
+	case *goast.AssignStmt:
+		return fromGoAssign(s)
+	case *goast.IncDecStmt:
+		x, err := fromGoName(s.X)
+		if err != nil {
+			return nil, err
+		}
+		return &IncDecStmt{X: x, TokPos: s.TokPos, Tok: s.Tok}, nil
+	case *goast.DeclStmt:
+		return fromGoDecl(s)
+	case *goast.IfStmt:
+		return fromGoIf(s)
+	case *goast.ForStmt:
+		return fromGoFor(s)
+	case *goast.BranchStmt:
+		if s.Label != nil || (s.Tok != token.BREAK && s.Tok != token.CONTINUE) {
+			return nil, convertError(s.Pos(), "unsupported %s statement", s.Tok)
+		}
+		return &BranchStmt{TokPos: s.TokPos, Tok: s.Tok}, nil
+	case *goast.EmptyStmt:
+		return nil, nil
+	}
+	return nil, convertError(s.Pos(), "unsupported statement")
+}
+
+// fromGoAssign converts := into a declaration with an inferred type, and keeps assignments.
+func fromGoAssign(a *goast.AssignStmt) (Stmt, error) {
+	if len(a.Lhs) != 1 || len(a.Rhs) != 1 {
+		return nil, convertError(a.Pos(), "expected a single value on each side of %s", a.Tok)
+	}
+	lhs, err := fromGoName(a.Lhs[0])
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := fromGoExpr(a.Rhs[0])
+	if err != nil {
+		return nil, err
+	}
+	switch a.Tok {
+	case token.DEFINE:
+		return &VarDecl{Decl: lhs.NamePos, Name: lhs, Value: rhs}, nil
+	case token.ASSIGN, token.ADD_ASSIGN, token.SUB_ASSIGN, token.MUL_ASSIGN, token.QUO_ASSIGN:
+		return &AssignStmt{Lhs: lhs, TokPos: a.TokPos, Tok: a.Tok, Rhs: rhs}, nil
+	}
+	return nil, convertError(a.TokPos, "unsupported operator %s", a.Tok)
+}
+
+// fromGoName converts the target of an assignment, which must be a name.
+func fromGoName(e goast.Expr) (*Ident, error) {
+	id, ok := e.(*goast.Ident)
+	if !ok || id.Name == "_" {
+		return nil, convertError(e.Pos(), "expected a name")
+	}
+	return fromGoIdent(id), nil
+}
+
+// fromGoDecl converts var into a declaration and const into a val. Vars without a value are zero, as in Go.
+func fromGoDecl(d *goast.DeclStmt) (Stmt, error) {
+	gen, ok := d.Decl.(*goast.GenDecl)
+	if !ok || (gen.Tok != token.VAR && gen.Tok != token.CONST) {
+		return nil, convertError(d.Pos(), "unsupported declaration")
+	}
+	if len(gen.Specs) != 1 || len(gen.Specs[0].(*goast.ValueSpec).Names) != 1 {
+		return nil, convertError(d.Pos(), "expected a single name in the declaration")
+	}
+	spec := gen.Specs[0].(*goast.ValueSpec)
+	decl := &VarDecl{Decl: gen.TokPos, Name: fromGoIdent(spec.Names[0]), Final: gen.Tok == token.CONST}
+
+	var typ *Ident
+	if spec.Type != nil {
+		var err error
+		typ, err = fromGoType(spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		decl.Type = typ
+	}
+	if len(spec.Values) == 0 {
+		decl.Value = zero(typ)
+		return decl, nil
+	}
+	if len(spec.Values) != 1 {
+		return nil, convertError(spec.Values[1].Pos(), "expected a single value")
+	}
+	value, err := fromGoExpr(spec.Values[0])
+	if err != nil {
+		return nil, err
+	}
+	decl.Value = value
+	return decl, nil
+}
+
+// zero returns the zero value of a converted type.
+func zero(typ *Ident) Expr {
+	switch typ.Name {
+	case "flt":
+		return &BasicLit{ValuePos: typ.NamePos, Kind: token.FLOAT, Value: "0.0"}
+	case "bool":
+		return &Ident{NamePos: typ.NamePos, Name: "false"}
+	}
+	return &BasicLit{ValuePos: typ.NamePos, Kind: token.INT, Value: "0"}
+}
+
+// fromGoIf converts an if statement. An init statement is moved into a block around the if.
+func fromGoIf(i *goast.IfStmt) (Stmt, error) {
+	cond, err := fromGoExpr(i.Cond)
+	if err != nil {
+		return nil, err
+	}
+	body, err := fromGoBlock(i.Body)
+	if err != nil {
+		return nil, err
+	}
+	n := &IfStmt{If: i.If, Cond: cond, Body: body}
+	if i.Else != nil {
+		n.Else, err = fromGoStmt(i.Else)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if i.Init == nil {
+		return n, nil
+	}
+	init, err := fromGoStmt(i.Init)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStmt{Lbrace: i.If, List: []Stmt{init, n}, Rbrace: i.End()}, nil
+}
+
+func fromGoFor(f *goast.ForStmt) (Stmt, error) {
+	n := &ForStmt{For: f.For}
+	var err error
+	if f.Init != nil {
+		n.Init, err = fromGoStmt(f.Init)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.Cond != nil {
+		n.Cond, err = fromGoExpr(f.Cond)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.Post != nil {
+		n.Post, err = fromGoStmt(f.Post)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n.Body, err = fromGoBlock(f.Body)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// goOps are the binary operators of Go that have the same meaning in Simple. &^ is missing, as its token stands in for >>>.
+var goOps = map[token.Token]bool{
+	token.ADD: true, token.SUB: true, token.MUL: true, token.QUO: true,
+	token.EQL: true, token.NEQ: true, token.LSS: true, token.GTR: true, token.LEQ: true, token.GEQ: true,
+	token.LAND: true, token.LOR: true, token.AND: true, token.OR: true, token.XOR: true, token.SHL: true, token.SHR: true,
+}
+
+func fromGoExpr(e goast.Expr) (Expr, error) {
+	switch e := e.(type) {
+	case *goast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return nil, convertError(e.Pos(), "unsupported literal %s", e.Value)
+		}
+		return &BasicLit{ValuePos: e.ValuePos, Kind: e.Kind, Value: e.Value}, nil
+	case *goast.Ident:
+		if e.Name == "nil" || e.Name == "_" {
+			return nil, convertError(e.Pos(), "unsupported name %s", e.Name)
+		}
+		return fromGoIdent(e), nil
+	case *goast.ParenExpr:
+		x, err := fromGoExpr(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Lparen: e.Lparen, X: x, Rparen: e.Rparen}, nil
+	case *goast.UnaryExpr:
+		x, err := fromGoExpr(e.X)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x, nil
+		case token.SUB, token.NOT, token.XOR:
+			return &UnaryExpr{OpPos: e.OpPos, Op: e.Op, X: x}, nil
+		}
+		return nil, convertError(e.OpPos, "unsupported operator %s", e.Op)
+	case *goast.BinaryExpr:
+		if !goOps[e.Op] {
+			return nil, convertError(e.OpPos, "unsupported operator %s", e.Op)
+		}
+		x, err := fromGoExpr(e.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := fromGoExpr(e.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{X: x, OpPos: e.OpPos, Op: e.Op, Y: y}, nil
+	case *goast.CallExpr:
+		return fromGoConversion(e)
+	}
+	return nil, convertError(e.Pos(), "unsupported expression")
+}
+
+// fromGoConversion converts the conversions int(x) and float64(x), which are the only calls in the subset.
+func fromGoConversion(c *goast.CallExpr) (Expr, error) {
+	fun, ok := c.Fun.(*goast.Ident)
+	if !ok || (fun.Name != "int" && fun.Name != "float64") {
+		return nil, convertError(c.Pos(), "unsupported call, only int and float64 conversions are supported")
+	}
+	if len(c.Args) != 1 {
+		return nil, convertError(c.Lparen, "%s expects 1 argument", fun.Name)
+	}
+	typ, err := fromGoType(fun)
+	if err != nil {
+		return nil, err
+	}
+	arg, err := fromGoExpr(c.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	return &CallExpr{Fun: typ, Lparen: c.Lparen, Args: []Expr{arg}, Rparen: c.Rparen}, nil
+}
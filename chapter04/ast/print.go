@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"go/token"
+	"strings"
+)
+
+// ExprString returns the expression written in Simple. Binary operations are parenthesized, so the result shows how the expression was grouped.
+func ExprString(e Expr) string {
+	sb := &strings.Builder{}
+	writeExpr(sb, e)
+	return sb.String()
+}
+
+func writeExpr(sb *strings.Builder, e Expr) {
+	switch e := e.(type) {
+	case *Ident:
+		sb.WriteString(e.Name)
+	case *BasicLit:
+		sb.WriteString(e.Value)
+	case *UnaryExpr:
+		sb.WriteString(e.Op.String())
+		writeExpr(sb, e.X)
+	case *BinaryExpr:
+		sb.WriteString("(")
+		writeExpr(sb, e.X)
+		sb.WriteString(opString(e.Op))
+		writeExpr(sb, e.Y)
+		sb.WriteString(")")
+	case *ParenExpr:
+		sb.WriteString("(")
+		writeExpr(sb, e.X)
+		sb.WriteString(")")
+	case *CallExpr:
+		sb.WriteString(e.Fun.Name)
+		sb.WriteString("(")
+		for i, arg := range e.Args {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			writeExpr(sb, arg)
+		}
+		sb.WriteString(")")
+	case *NewExpr:
+		sb.WriteString("new ")
+		sb.WriteString(e.Type.Name)
+		if e.Len != nil {
+			sb.WriteString("[")
+			writeExpr(sb, e.Len)
+			sb.WriteString("]")
+		}
+	case *FieldExpr:
+		writeExpr(sb, e.X)
+		sb.WriteString(".")
+		sb.WriteString(e.Field.Name)
+	case *LenExpr:
+		writeExpr(sb, e.X)
+		sb.WriteString("#")
+	case *IndexExpr:
+		writeExpr(sb, e.X)
+		sb.WriteString("[")
+		writeExpr(sb, e.Index)
+		sb.WriteString("]")
+	case *CondExpr:
+		sb.WriteString("(")
+		writeExpr(sb, e.Cond)
+		sb.WriteString("?")
+		writeExpr(sb, e.X)
+		sb.WriteString(":")
+		writeExpr(sb, e.Y)
+		sb.WriteString(")")
+	}
+}
+
+func opString(op token.Token) string {
+	if op == LShr {
+		return ">>>"
+	}
+	return op.String()
+}
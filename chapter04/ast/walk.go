@@ -0,0 +1,132 @@
+package ast
+
+import "fmt"
+
+// Visitor visits the nodes of a syntax tree with Walk. If Visit returns a visitor w, the children of the node are visited with w, followed by w.Visit(nil).
+type Visitor interface {
+	Visit(n Node) (w Visitor)
+}
+
+// Walk traverses the syntax tree of n in depth first order, in source order of the children.
+func Walk(v Visitor, n Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+
+	switch n := n.(type) {
+	case *Ident, *BasicLit, *BranchStmt, *Comment:
+		// No children
+	case *UnaryExpr:
+		Walk(v, n.X)
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *ParenExpr:
+		Walk(v, n.X)
+	case *CallExpr:
+		Walk(v, n.Fun)
+		walkList(v, n.Args)
+	case *NewExpr:
+		Walk(v, n.Type)
+		if n.Len != nil {
+			Walk(v, n.Len)
+		}
+	case *FieldExpr:
+		Walk(v, n.X)
+		Walk(v, n.Field)
+	case *LenExpr:
+		Walk(v, n.X)
+	case *IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+	case *CondExpr:
+		Walk(v, n.Cond)
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *ArrayType:
+		Walk(v, n.Elt)
+	case *NullableType:
+		Walk(v, n.X)
+	case *ReturnStmt:
+		Walk(v, n.Result)
+	case *BlockStmt:
+		walkList(v, n.List)
+	case *VarDecl:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		Walk(v, n.Name)
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *FuncDecl:
+		Walk(v, n.Result)
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+		Walk(v, n.Body)
+	case *StructDecl:
+		Walk(v, n.Name)
+		walkList(v, n.Fields)
+	case *ImportDecl:
+		Walk(v, n.Path)
+	case *Field:
+		Walk(v, n.Type)
+		Walk(v, n.Name)
+	case *AssignStmt:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+	case *IncDecStmt:
+		Walk(v, n.X)
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *ForStmt:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		if n.Post != nil {
+			Walk(v, n.Post)
+		}
+		Walk(v, n.Body)
+	case *SwitchStmt:
+		Walk(v, n.Tag)
+		walkList(v, n.Cases)
+	case *CaseClause:
+		walkList(v, n.List)
+		Walk(v, n.Body)
+	case *InstructionStmt:
+		Walk(v, n.Name)
+	case *CommentGroup:
+		walkList(v, n.List)
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkList[N Node](v Visitor, list []N) {
+	for _, n := range list {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(n Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the syntax tree of n in depth first order. f is called for every node, and the children of a node are only visited if f returns true. f is called with nil after the children.
+func Inspect(n Node, f func(Node) bool) {
+	Walk(inspector(f), n)
+}
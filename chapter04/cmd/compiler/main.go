@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	simple "github.com/SeaOfNodes/Simple-Go/chapter04"
@@ -15,12 +16,20 @@ func main() {
 	useGoAST := flag.Bool("a", false, "")
 	printString := flag.Bool("s", false, "")
 	disablePeephole := flag.Bool("d", false, "")
+	color := flag.Bool("c", false, "")
+	jumpTables := flag.Bool("j", false, "")
+	zeroInit := flag.Bool("z", false, "")
+	file := flag.Bool("f", false, "")
 	flag.Usage = func() {
 		fmt.Println("Simple compiler written in Go. Prints graph representation of IR.")
-		fmt.Printf("Usage: %s [-a] [-d] [-s] <code> [arg]\n", os.Args[0])
+		fmt.Printf("Usage: %s [-a] [-c] [-d] [-f] [-j] [-s] [-z] <code> [arg]\n", os.Args[0])
 		fmt.Println("\t-a\tUse Go AST parser")
+		fmt.Println("\t-c\tColorize errors")
 		fmt.Println("\t-d\tDisable peephole optimizations")
+		fmt.Println("\t-f\tRead the code from the file <code>, and import modules from its directory")
+		fmt.Println("\t-j\tLower dense switches to jump tables")
 		fmt.Println("\t-s\tPrint string visualization")
+		fmt.Println("\t-z\tInitialize variables declared without a value to zero")
 		fmt.Println("\t-h\tPrint this help and exit")
 	}
 	flag.Parse()
@@ -30,6 +39,16 @@ func main() {
 		return
 	}
 	code := flag.Args()[0]
+	path := ""
+	if *file {
+		path = code
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Cannot read code: %v\n", err)
+			return
+		}
+		code = string(source)
+	}
 	var arg any
 	if len(flag.Args()) > 1 {
 		var err error
@@ -44,25 +63,46 @@ func main() {
 	if *disablePeephole {
 		ir.DisablePeephole = true
 	}
+	if *zeroInit {
+		ir.ZeroInitialize = true
+	}
+	if *jumpTables {
+		ir.JumpTables = true
+	}
 
-	var node ir.Node
+	var ret *ir.ReturnNode
 	var generator *ir.Generator
 	var err error
 	if *useGoAST {
-		node, generator, err = simple.GoSimple(code, arg)
-		if err != nil {
-			log.Fatalf("Compiler error: %v", err)
-		}
+		ret, generator, err = simple.GoSimple(code, arg)
+	} else if *file {
+		ret, generator, err = simple.SimpleFile(path, code, simple.DirResolver{Dir: filepath.Dir(path)}, arg)
 	} else {
-		node, generator, err = simple.Simple(code, arg)
-		if err != nil {
-			log.Fatalf("Compiler error: %v", err)
+		ret, generator, err = simple.Simple(code, arg)
+	}
+	if err != nil {
+		// Source errors show where in the code they happened
+		if r, ok := err.(interface{ Render(bool) string }); ok {
+			log.Fatalf("Compiler error: %s", r.Render(*color))
 		}
+		log.Fatalf("Compiler error: %v", err)
 	}
 
 	if *printString {
-		fmt.Printf("String:\n\n%s", ir.ToString(node))
+		fmt.Printf("String:\n\n")
+		for _, fun := range generator.Functions {
+			fmt.Printf("%s: %s\n", fun.Name, returnString(fun.Ret))
+		}
+		fmt.Printf("%s", returnString(ret))
 	} else {
 		fmt.Printf("Graph:\n\n%s", ir.Visualize(generator))
 	}
 }
+
+// returnString returns the string visualization of a return, which is nil for code that never returns.
+func returnString(ret *ir.ReturnNode) string {
+	if ret == nil {
+		return "never returns"
+	}
+	return ir.ToString(ret)
+}
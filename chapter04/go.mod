@@ -2,11 +2,13 @@ module github.com/SeaOfNodes/Simple-Go/chapter04
 
 go 1.22.0
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.9.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,131 @@
+package simple
+
+import (
+	goast "go/ast"
+	goParser "go/parser"
+	"go/scanner"
+	"go/token"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir"
+	"github.com/pkg/errors"
+)
+
+// goPrefix turns a function declaration into a Go file. It is removed from offsets, so errors point into the source.
+const goPrefix = "package simple\n"
+
+// GoSimple compiles a Go function such as func f(arg int) int { ... } with the go/ast parser. The subset of Go that maps onto Simple is supported: :=, var, const, assignments, return, blocks, if and for. A single expression is compiled as if it was returned.
+func GoSimple(source string, arg any) (*ir.ReturnNode, *ir.Generator, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", 1, len(source))
+	file.SetLinesForContent([]byte(source))
+	t := &goTranslator{source: source, file: file, fset: token.NewFileSet()}
+
+	body, result, err := t.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generator := ir.NewGenerator(getArgType(arg))
+	main := &ir.Module{Body: body}
+	// Expressions have no declared result
+	if result != nil {
+		main.Result = result
+	}
+	ret, err := generator.GenerateModule(main)
+	if err != nil {
+		// Enrich ast errors with source info
+		if a, ok := err.(*ir.ASTError); ok {
+			return nil, nil, &SourceError{a, source, t.offset(a.Pos), file}
+		}
+		return nil, nil, err
+	}
+	return ret, generator, nil
+}
+
+// goTranslator parses Go source and converts it into the syntax tree of Simple. Positions are kept, so errors point into the Go source.
+type goTranslator struct {
+	source string
+	// file holds the lines of the source
+	file *token.File
+	// fset holds the positions of the nodes parsed by go/parser
+	fset *token.FileSet
+	// prefix is the length of the text added before the source to parse it
+	prefix int
+}
+
+// parse returns the converted program, and the declared type of its result if the source is a function.
+func (t *goTranslator) parse() (*ast.BlockStmt, *ast.Ident, error) {
+	if !isFunc(t.source) {
+		expr, err := goParser.ParseExprFrom(t.fset, "", t.source, 0)
+		if err != nil {
+			return nil, nil, t.syntaxErrors(err)
+		}
+		body, err := t.convert(ast.FromGoExpr(expr))
+		return body, nil, err
+	}
+
+	t.prefix = len(goPrefix)
+	f, err := goParser.ParseFile(t.fset, "", goPrefix+t.source, 0)
+	if err != nil {
+		return nil, nil, t.syntaxErrors(err)
+	}
+	if len(f.Decls) == 0 {
+		return nil, nil, t.errorf(f.FileEnd, "expected a function")
+	}
+	if len(f.Decls) != 1 {
+		return nil, nil, t.errorf(f.Decls[len(f.Decls)-1].Pos(), "expected a single function")
+	}
+	fun, ok := f.Decls[0].(*goast.FuncDecl)
+	if !ok {
+		return nil, nil, t.errorf(f.Decls[0].Pos(), "expected a function")
+	}
+	body, result, err := ast.FromGoFunc(fun)
+	body, err = t.convert(body, err)
+	return body, result, err
+}
+
+// isFunc reports whether the source starts with the func keyword, so it is a function declaration rather than an expression.
+func isFunc(source string) bool {
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", fset.Base(), len(source)), []byte(source), nil, 0)
+	_, tok, _ := s.Scan()
+	return tok == token.FUNC
+}
+
+// convert returns the converted program, with conversion errors as source errors.
+func (t *goTranslator) convert(b *ast.BlockStmt, err error) (*ast.BlockStmt, error) {
+	if e, ok := err.(*ast.ConvertError); ok {
+		return nil, &SourceError{e, t.source, t.offset(e.Pos), t.file}
+	}
+	return b, err
+}
+
+// offset returns the offset of a position of go/parser in the source.
+func (t *goTranslator) offset(pos token.Pos) int {
+	return t.fset.Position(pos).Offset - t.prefix
+}
+
+func (t *goTranslator) errorf(pos token.Pos, msgFormat string, args ...any) *SourceError {
+	internal := errors.Errorf("Syntax error: "+msgFormat, args...)
+	return &SourceError{internal, t.source, t.offset(pos), t.file}
+}
+
+// syntaxErrors converts the errors of go/parser into source errors.
+func (t *goTranslator) syntaxErrors(err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return err
+	}
+	errs := make(SourceErrors, len(list))
+	for i, e := range list {
+		// Errors at the end of the source are reported after the prefix
+		offset := min(max(e.Pos.Offset-t.prefix, 0), len(t.source))
+		errs[i] = &SourceError{errors.New("Syntax error: " + e.Msg), t.source, offset, t.file}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
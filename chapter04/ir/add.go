@@ -30,11 +30,15 @@ func (a *AddNode) compute() (types.Type, error) {
 	if lType.Constant() && rType.Constant() {
 		return types.NewInt(lType.Value + rType.Value), nil
 	}
+	// The sum of sized ints may not fit in their width until the generator wraps it
+	if lType.Sized() || rType.Sized() {
+		return types.IntBottom, nil
+	}
 	return lType.Meet(rType), nil
 }
 
 func (a *AddNode) idealize() (Node, error) {
-	if c, ok := Type(a.Rhs()).(*types.Int); ok && c.Value == 0 {
+	if c, ok := Type(a.Rhs()).(*types.Int); ok && c.Constant() && c.Value == 0 {
 		return a.Lhs(), nil
 	}
 
@@ -0,0 +1,51 @@
+package ir
+
+import (
+	"math"
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// AddFNode adds two floats.
+type AddFNode struct {
+	binaryNode
+}
+
+func NewAddFNode(lhs Node, rhs Node) *AddFNode {
+	return initBinaryNode(&AddFNode{}, lhs, rhs)
+}
+
+func (a *AddFNode) GraphicLabel() string { return "+" }
+func (a *AddFNode) label() string        { return "AddF" }
+
+func (a *AddFNode) compute() (types.Type, error) {
+	lhs, lOk := fltConstant(a.Lhs())
+	rhs, rOk := fltConstant(a.Rhs())
+	if lOk && rOk {
+		return types.NewFlt(lhs + rhs), nil
+	}
+	return types.FltBottom, nil
+}
+
+// idealize only applies rules that are exact for every float, including NaN and -0.0. For example x+0.0 is not x when x is -0.0.
+func (a *AddFNode) idealize() (Node, error) {
+	// x + -0.0 => x
+	if isFltConstant(a.Rhs(), math.Copysign(0, -1)) {
+		return a.Lhs(), nil
+	}
+	// Move constants to the rhs
+	if Type(a.Lhs()).Constant() && !Type(a.Rhs()).Constant() {
+		a.swap()
+		return a, nil
+	}
+	return nil, nil
+}
+
+func (a *AddFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(a.Lhs(), sb)
+	sb.WriteString("+")
+	toString(a.Rhs(), sb)
+	sb.WriteString(")")
+}
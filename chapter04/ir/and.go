@@ -0,0 +1,79 @@
+package ir
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+type AndNode struct {
+	binaryNode
+}
+
+func NewAndNode(lhs Node, rhs Node) *AndNode {
+	return initBinaryNode(&AndNode{}, lhs, rhs)
+}
+
+func (a *AndNode) GraphicLabel() string { return "&" }
+func (a *AndNode) label() string        { return "And" }
+
+func (a *AndNode) compute() (types.Type, error) {
+	// x&0=>0
+	if isIntConstant(a.Lhs(), 0) || isIntConstant(a.Rhs(), 0) {
+		return types.NewInt(0), nil
+	}
+	lhs, lOk := intConstant(a.Lhs())
+	rhs, rOk := intConstant(a.Rhs())
+	switch {
+	case lOk && rOk:
+		return types.NewInt(lhs & rhs), nil
+	// Masking with a positive constant keeps the value between 0 and the mask
+	case lOk && lhs > 0:
+		return types.FitInt(0, lhs), nil
+	case rOk && rhs > 0:
+		return types.FitInt(0, rhs), nil
+	}
+	return types.Bottom, nil
+}
+
+func (a *AndNode) idealize() (Node, error) {
+	// x&-1=>x, x&x=>x
+	if isIntConstant(a.Rhs(), -1) || a.Lhs() == a.Rhs() {
+		return a.Lhs(), nil
+	}
+
+	// x&c=>x when x is positive and c keeps all the bits x can have, like u8(x)&255
+	if c, ok := intConstant(a.Rhs()); ok {
+		if x, ok := Type(a.Lhs()).(*types.Int); ok && !x.Constant() && x.Min() >= 0 {
+			mask := 1<<bits.Len(uint(x.Max())) - 1
+			if c&mask == mask {
+				return a.Lhs(), nil
+			}
+		}
+	}
+
+	if Type(a.Lhs()).Constant() && !Type(a.Rhs()).Constant() {
+		a.swap()
+		return a, nil
+	}
+
+	// (x&c1)&c2=>x&(c1&c2)
+	if l, ok := a.Lhs().(*AndNode); ok && Type(l.Rhs()).Constant() && Type(a.Rhs()).Constant() {
+		rhs, err := peephole(NewAndNode(l.Rhs(), a.Rhs()))
+		if err != nil {
+			return nil, err
+		}
+		return NewAndNode(l.Lhs(), rhs), nil
+	}
+
+	return nil, nil
+}
+
+func (a *AndNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(a.Lhs(), sb)
+	sb.WriteString("&")
+	toString(a.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,48 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// AShrNode is the arithmetic shift right, which copies the sign bit. Only the lowest 6 bits of the rhs are used, as in Java.
+type AShrNode struct {
+	binaryNode
+}
+
+func NewAShrNode(lhs Node, rhs Node) *AShrNode {
+	return initBinaryNode(&AShrNode{}, lhs, rhs)
+}
+
+func (a *AShrNode) GraphicLabel() string { return ">>" }
+func (a *AShrNode) label() string        { return "AShr" }
+
+func (a *AShrNode) compute() (types.Type, error) {
+	// 0>>x=>0, -1>>x=>-1
+	if isIntConstant(a.Lhs(), 0) || isIntConstant(a.Lhs(), -1) {
+		return Type(a.Lhs()), nil
+	}
+	lhs, lOk := intConstant(a.Lhs())
+	rhs, rOk := shiftAmount(a.Rhs())
+	if !lOk || !rOk {
+		return types.Bottom, nil
+	}
+	return types.NewInt(lhs >> rhs), nil
+}
+
+func (a *AShrNode) idealize() (Node, error) {
+	// x>>0=>x
+	if rhs, ok := shiftAmount(a.Rhs()); ok && rhs == 0 {
+		return a.Lhs(), nil
+	}
+	return nil, nil
+}
+
+func (a *AShrNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(a.Lhs(), sb)
+	sb.WriteString(">>")
+	toString(a.Rhs(), sb)
+	sb.WriteString(")")
+}
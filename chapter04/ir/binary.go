@@ -1,5 +1,11 @@
 package ir
 
+import (
+	"math"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
 type binaryNode struct {
 	baseNode
 }
@@ -25,3 +31,39 @@ func (b *binaryNode) IsControl() bool { return false }
 func (b *binaryNode) Lhs() Node { return b.ins[0] }
 func (b *binaryNode) Rhs() Node { return b.ins[1] }
 func (b *binaryNode) swap()     { b.ins[0], b.ins[1] = b.ins[1], b.ins[0] }
+
+// fltConstant returns the value of n if it is a constant float.
+func fltConstant(n Node) (float64, bool) {
+	f, ok := Type(n).(*types.Flt)
+	if !ok || !f.Constant() {
+		return 0, false
+	}
+	return f.Value, true
+}
+
+// isFltConstant returns true if n is the constant float value. The bits are compared, so 0.0 and -0.0 are different.
+func isFltConstant(n Node, value float64) bool {
+	f, ok := fltConstant(n)
+	return ok && math.Float64bits(f) == math.Float64bits(value)
+}
+
+// intConstant returns the value of n if it is a constant int.
+func intConstant(n Node) (int, bool) {
+	i, ok := Type(n).(*types.Int)
+	if !ok || !i.Constant() {
+		return 0, false
+	}
+	return i.Value, true
+}
+
+// isIntConstant returns true if n is the constant int value.
+func isIntConstant(n Node, value int) bool {
+	i, ok := intConstant(n)
+	return ok && i == value
+}
+
+// shiftAmount returns the constant amount of a shift. Only the lowest 6 bits of the amount are used, so shifts are by 0-63.
+func shiftAmount(n Node) (uint, bool) {
+	i, ok := intConstant(n)
+	return uint(i) & 63, ok
+}
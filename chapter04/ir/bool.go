@@ -24,35 +24,43 @@ func NewBoolNode(lhs Node, op BoolType, rhs Node) *BoolNode {
 }
 
 func (b *BoolNode) doOp(lhs int, rhs int) types.Type {
-	val := false
 	switch b.op {
-	case EQ:
-		val = lhs == rhs
 	case LT:
-		val = lhs < rhs
+		return types.NewBool(lhs < rhs)
 	case LE:
-		val = lhs <= rhs
-	}
-	if val {
-		return types.NewInt(1)
+		return types.NewBool(lhs <= rhs)
 	}
-	return types.NewInt(0)
+	return types.NewBool(lhs == rhs)
 }
 
+// compute compares ints, or bools and references for equality.
 func (b *BoolNode) compute() (types.Type, error) {
-	lType, ok := Type(b.Lhs()).(*types.Int)
-	if !ok {
-		return types.Bottom, nil
-	}
-	rType, ok := Type(b.Rhs()).(*types.Int)
-	if !ok {
-		return types.Bottom, nil
-	}
-
-	if lType.Constant() && rType.Constant() {
-		return b.doOp(lType.Value, rType.Value), nil
+	switch lType := Type(b.Lhs()).(type) {
+	case *types.Int:
+		rType, ok := Type(b.Rhs()).(*types.Int)
+		if ok && lType.Constant() && rType.Constant() {
+			return b.doOp(lType.Value, rType.Value), nil
+		}
+	case *types.Bool:
+		rType, ok := Type(b.Rhs()).(*types.Bool)
+		if ok && lType.Constant() && rType.Constant() && b.op == EQ {
+			return types.NewBool(lType.Value == rType.Value), nil
+		}
+	case *types.MemPtr:
+		rType, ok := Type(b.Rhs()).(*types.MemPtr)
+		if !ok {
+			break
+		}
+		// Constant references are null and string literals, which are unique by value
+		if lType.Constant() && rType.Constant() {
+			return types.NewBool(lType == rType), nil
+		}
+		// null is never equal to a reference that is not null
+		if (lType == types.Null || rType == types.Null) && (!lType.Nullable || !rType.Nullable) {
+			return types.False, nil
+		}
 	}
-	return lType.Meet(rType), nil
+	return types.BoolBottom, nil
 }
 
 func (b *BoolNode) idealize() (Node, error) {
@@ -0,0 +1,57 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// BoolFNode compares two floats. Every comparison with NaN is false.
+type BoolFNode struct {
+	binaryNode
+	op BoolType
+}
+
+func NewBoolFNode(lhs Node, op BoolType, rhs Node) *BoolFNode {
+	return initBinaryNode(&BoolFNode{op: op}, lhs, rhs)
+}
+
+func (b *BoolFNode) GraphicLabel() string { return string(b.op) }
+func (b *BoolFNode) label() string {
+	switch b.op {
+	case EQ:
+		return "eqF"
+	case LE:
+		return "leF"
+	case LT:
+		return "ltF"
+	}
+	return ""
+}
+
+func (b *BoolFNode) compute() (types.Type, error) {
+	lhs, lOk := fltConstant(b.Lhs())
+	rhs, rOk := fltConstant(b.Rhs())
+	if !lOk || !rOk {
+		return types.BoolBottom, nil
+	}
+
+	switch b.op {
+	case LT:
+		return types.NewBool(lhs < rhs), nil
+	case LE:
+		return types.NewBool(lhs <= rhs), nil
+	}
+	return types.NewBool(lhs == rhs), nil
+}
+
+// idealize does not fold x==x, since NaN is not equal to itself.
+func (b *BoolFNode) idealize() (Node, error) { return nil, nil }
+
+func (b *BoolFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(b.Lhs(), sb)
+	sb.WriteString(string(b.op))
+	toString(b.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,55 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// BoundsCheckNode checks that an index is within the length of an array. Its inputs are the control, the index and the length, and its result is the checked index. The check is removed once the index is known to be in bounds.
+type BoundsCheckNode struct {
+	baseNode
+}
+
+func NewBoundsCheckNode(control Node, index Node, length Node) *BoundsCheckNode {
+	return initBaseNode(&BoundsCheckNode{}, control, index, length)
+}
+
+func (b *BoundsCheckNode) Index() Node  { return In(b, 1) }
+func (b *BoundsCheckNode) Length() Node { return In(b, 2) }
+
+func (b *BoundsCheckNode) IsControl() bool      { return false }
+func (b *BoundsCheckNode) GraphicLabel() string { return "chk" }
+func (b *BoundsCheckNode) label() string        { return "BoundsCheck" }
+
+func (b *BoundsCheckNode) compute() (types.Type, error) {
+	if b.inBounds() {
+		return Type(b.Index()), nil
+	}
+	return types.IntBottom, nil
+}
+
+func (b *BoundsCheckNode) idealize() (Node, error) {
+	if b.inBounds() {
+		return b.Index(), nil
+	}
+	return nil, nil
+}
+
+// inBounds returns true if the range of the index is known to be within the smallest length, like a u8 index of an array of length 256.
+func (b *BoundsCheckNode) inBounds() bool {
+	index, ok := Type(b.Index()).(*types.Int)
+	if !ok || index.Top() {
+		return false
+	}
+	length, ok := Type(b.Length()).(*types.Int)
+	if !ok || length.Top() {
+		return false
+	}
+	return index.Min() >= 0 && index.Max() < length.Min()
+}
+
+// toStringInternal prints the checked index, the check itself is only visible in the graph.
+func (b *BoundsCheckNode) toStringInternal(sb *strings.Builder) {
+	toString(b.Index(), sb)
+}
@@ -0,0 +1,93 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// CallNode calls a function. Its inputs are the control, the called function and the arguments.
+type CallNode struct {
+	baseNode
+}
+
+func NewCallNode(control Node, fun *FunNode, args ...Node) *CallNode {
+	return initBaseNode(&CallNode{}, append([]Node{control, fun}, args...)...)
+}
+
+func (c *CallNode) Control() Node   { return In(c, 0) }
+func (c *CallNode) Fun() *FunNode   { return In(c, 1).(*FunNode) }
+func (c *CallNode) Args() []Node    { return Ins(c)[2:] }
+func (c *CallNode) IsControl() bool { return true }
+
+func (c *CallNode) GraphicLabel() string { return "Call " + c.Fun().Name }
+func (c *CallNode) label() string        { return "Call" }
+
+func (c *CallNode) compute() (types.Type, error) {
+	if c.Control() == nil || Type(c.Control()) != types.Control {
+		return types.Top, nil
+	}
+	return types.Control, nil
+}
+
+func (c *CallNode) idealize() (Node, error) { return nil, nil }
+
+func (c *CallNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString(c.Fun().Name)
+	sb.WriteString("(")
+	for i, arg := range c.Args() {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		toString(arg, sb)
+	}
+	sb.WriteString(")")
+}
+
+// CallEndNode is where control returns to after a call. Its inputs are the call and the return of the called function, which is linked once the function was generated. Its result is a tuple of the control and the returned value.
+type CallEndNode struct {
+	baseNode
+}
+
+func NewCallEndNode(call *CallNode) *CallEndNode {
+	var ret Node
+	if call.Fun().Ret != nil {
+		ret = call.Fun().Ret
+	}
+	return initBaseNode(&CallEndNode{}, call, ret)
+}
+
+func (c *CallEndNode) Call() *CallNode { return In(c, 0).(*CallNode) }
+func (c *CallEndNode) IsControl() bool { return true }
+
+func (c *CallEndNode) GraphicLabel() string { return "CallEnd" }
+func (c *CallEndNode) label() string        { return "CallEnd" }
+
+func (c *CallEndNode) multinode() {}
+
+// link sets the return of the called function, for calls that were generated before the function itself.
+func (c *CallEndNode) link() error {
+	ret := c.Call().Fun().Ret
+	if ret == nil || In(c, 1) == ret {
+		return nil
+	}
+	return setIn(c, 1, ret)
+}
+
+func (c *CallEndNode) compute() (types.Type, error) {
+	if Type(c.Call()) != types.Control {
+		return types.NewTuple(types.Top, types.Top), nil
+	}
+	// The returned value is only known once the called function is linked
+	value := c.Call().Fun().RetType
+	if ret, ok := In(c, 1).(*ReturnNode); ok {
+		value = Type(ret.Expr())
+	}
+	return types.NewTuple(types.Control, value), nil
+}
+
+func (c *CallEndNode) idealize() (Node, error) { return nil, nil }
+
+func (c *CallEndNode) toStringInternal(sb *strings.Builder) {
+	toString(c.Call(), sb)
+}
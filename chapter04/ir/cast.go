@@ -0,0 +1,49 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// CastNode narrows the type of a reference on the paths where a check proved it, like a reference that is not null after if (p). It depends on the control of the check, so it is not moved above it.
+type CastNode struct {
+	baseNode
+	ptr *types.MemPtr
+}
+
+func NewCastNode(control Node, ptr *types.MemPtr, value Node) *CastNode {
+	return initBaseNode(&CastNode{ptr: ptr}, control, value)
+}
+
+func (c *CastNode) Control() Node { return In(c, 0) }
+func (c *CastNode) Value() Node   { return In(c, 1) }
+
+func (c *CastNode) IsControl() bool { return false }
+func (c *CastNode) GraphicLabel() string {
+	var sb strings.Builder
+	c.ptr.ToString(&sb)
+	return "(" + sb.String() + ")"
+}
+func (c *CastNode) label() string { return "Cast" }
+
+// compute returns the values of the reference that are also values of the cast. A cast on a dead branch keeps the type of the cast, so that the dead code still type checks.
+func (c *CastNode) compute() (types.Type, error) {
+	if p, ok := Type(c.Value()).(*types.MemPtr); ok && c.Control() != nil {
+		return c.ptr.Join(p), nil
+	}
+	return c.ptr, nil
+}
+
+// idealize removes the cast of a reference that already has the type of the cast.
+func (c *CastNode) idealize() (Node, error) {
+	if p, ok := Type(c.Value()).(*types.MemPtr); ok && p.Isa(c.ptr) {
+		return c.Value(), nil
+	}
+	return nil, nil
+}
+
+func (c *CastNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString(c.GraphicLabel())
+	toString(c.Value(), sb)
+}
@@ -1,7 +1,6 @@
 package ir
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
@@ -20,12 +19,10 @@ func NewConstantNode(typ types.Type) *ConstantNode {
 func (c *ConstantNode) IsControl() bool      { return false }
 func (c *ConstantNode) GraphicLabel() string { return c.label() }
 
-func (c *ConstantNode) label() string                { return "#" + strconv.Itoa(c.value()) }
+func (c *ConstantNode) label() string                { return "#" + ToString(c) }
 func (c *ConstantNode) compute() (types.Type, error) { return c.typ, nil }
 func (c *ConstantNode) idealize() (Node, error)      { return nil, nil }
 
-func (c *ConstantNode) value() int { return c.typ.(*types.Int).Value }
-
 func (c *ConstantNode) toStringInternal(sb *strings.Builder) {
 	c.typ.ToString(sb)
 }
@@ -0,0 +1,84 @@
+package ir
+
+import (
+	"math"
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// ToFltNode converts an int to the nearest float.
+type ToFltNode struct {
+	baseNode
+}
+
+func NewToFltNode(value Node) *ToFltNode {
+	return initBaseNode(&ToFltNode{}, value)
+}
+
+func (t *ToFltNode) Value() Node { return In(t, 0) }
+
+func (t *ToFltNode) IsControl() bool      { return false }
+func (t *ToFltNode) GraphicLabel() string { return "flt" }
+func (t *ToFltNode) label() string        { return "ToFlt" }
+
+func (t *ToFltNode) compute() (types.Type, error) {
+	if i, ok := Type(t.Value()).(*types.Int); ok && i.Constant() {
+		return types.NewFlt(float64(i.Value)), nil
+	}
+	return types.FltBottom, nil
+}
+
+func (t *ToFltNode) idealize() (Node, error) { return nil, nil }
+
+func (t *ToFltNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("flt(")
+	toString(t.Value(), sb)
+	sb.WriteString(")")
+}
+
+// ToIntNode converts a float to an int by truncating towards zero. Floats out of the range of ints saturate to the smallest or largest int, and NaN converts to 0.
+type ToIntNode struct {
+	baseNode
+}
+
+func NewToIntNode(value Node) *ToIntNode {
+	return initBaseNode(&ToIntNode{}, value)
+}
+
+func (t *ToIntNode) Value() Node { return In(t, 0) }
+
+func (t *ToIntNode) IsControl() bool      { return false }
+func (t *ToIntNode) GraphicLabel() string { return "int" }
+func (t *ToIntNode) label() string        { return "ToInt" }
+
+func (t *ToIntNode) compute() (types.Type, error) {
+	if f, ok := fltConstant(t.Value()); ok {
+		return types.NewInt(fltToInt(f)), nil
+	}
+	return types.IntBottom, nil
+}
+
+// fltToInt converts f the same way at compile time on every platform, since Go leaves out of range conversions unspecified.
+func fltToInt(f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int(f)
+}
+
+func (t *ToIntNode) idealize() (Node, error) {
+	// int(flt(x)) => x is only exact for ints that fit in the mantissa, so it is left as is
+	return nil, nil
+}
+
+func (t *ToIntNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("int(")
+	toString(t.Value(), sb)
+	sb.WriteString(")")
+}
@@ -1,13 +1,14 @@
 package ir
 
 import (
-	"go/ast"
 	"strings"
 
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
 )
 
 type DivNode struct {
+	// expr is the divisor in the source, where division by zero is reported
 	expr ast.Expr
 	binaryNode
 }
@@ -20,26 +21,29 @@ func (d *DivNode) GraphicLabel() string { return "/" }
 func (d *DivNode) label() string        { return "Div" }
 
 func (d *DivNode) compute() (types.Type, error) {
-	lType, ok := Type(d.Lhs()).(*types.Int)
+	rType, ok := Type(d.Rhs()).(*types.Int)
 	if !ok {
 		return types.Bottom, nil
 	}
-	rType, ok := Type(d.Rhs()).(*types.Int)
+	// A constant divisor of zero fails whatever the dividend is
+	if rType.Constant() && rType.Value == 0 {
+		if d.expr != nil {
+			return nil, computeError(d.expr, "divide by zero")
+		}
+		return types.IntBottom, nil
+	}
+	lType, ok := Type(d.Lhs()).(*types.Int)
 	if !ok {
 		return types.Bottom, nil
 	}
-
 	if lType.Constant() && rType.Constant() {
-		if rType.Value == 0 {
-			return nil, computeError(d.expr, "divide by zero")
-		}
 		return types.NewInt(lType.Value / rType.Value), nil
 	}
 	return types.Bottom, nil
 }
 
 func (d *DivNode) idealize() (Node, error) {
-	if rType, ok := Type(d.Rhs()).(*types.Int); ok && rType.Value == 1 {
+	if rType, ok := Type(d.Rhs()).(*types.Int); ok && rType.Constant() && rType.Value == 1 {
 		return d.Lhs(), nil
 	}
 
@@ -0,0 +1,44 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// DivFNode divides two floats. Division by zero is not an error, it results in an infinity or NaN.
+type DivFNode struct {
+	binaryNode
+}
+
+func NewDivFNode(lhs Node, rhs Node) *DivFNode {
+	return initBinaryNode(&DivFNode{}, lhs, rhs)
+}
+
+func (d *DivFNode) GraphicLabel() string { return "/" }
+func (d *DivFNode) label() string        { return "DivF" }
+
+func (d *DivFNode) compute() (types.Type, error) {
+	lhs, lOk := fltConstant(d.Lhs())
+	rhs, rOk := fltConstant(d.Rhs())
+	if lOk && rOk {
+		return types.NewFlt(lhs / rhs), nil
+	}
+	return types.FltBottom, nil
+}
+
+func (d *DivFNode) idealize() (Node, error) {
+	// x / 1.0 => x
+	if isFltConstant(d.Rhs(), 1) {
+		return d.Lhs(), nil
+	}
+	return nil, nil
+}
+
+func (d *DivFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(d.Lhs(), sb)
+	sb.WriteString("/")
+	toString(d.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,91 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// SignExtNode truncates an int to the width of a signed sized int, then sign extends it back to 64 bits.
+type SignExtNode struct {
+	baseNode
+	size *types.Int
+}
+
+// TruncNode truncates an int to the width of an unsigned sized int, clearing the upper bits.
+type TruncNode struct {
+	baseNode
+	size *types.Int
+}
+
+// NewExtendNode returns the node that fits the value in the width of the sized int.
+func NewExtendNode(size *types.Int, value Node) Node {
+	if size.Unsigned() {
+		return initBaseNode(&TruncNode{size: size}, value)
+	}
+	return initBaseNode(&SignExtNode{size: size}, value)
+}
+
+func (s *SignExtNode) Value() Node { return In(s, 0) }
+
+func (s *SignExtNode) IsControl() bool      { return false }
+func (s *SignExtNode) GraphicLabel() string { return s.size.Name() }
+func (s *SignExtNode) label() string        { return "SignExt" }
+
+func (s *SignExtNode) compute() (types.Type, error) { return computeExtend(s.size, s.Value()), nil }
+func (s *SignExtNode) idealize() (Node, error)      { return idealizeExtend(s, s.size) }
+
+func (s *SignExtNode) toStringInternal(sb *strings.Builder) { extendToString(s.size, s.Value(), sb) }
+
+func (t *TruncNode) Value() Node { return In(t, 0) }
+
+func (t *TruncNode) IsControl() bool      { return false }
+func (t *TruncNode) GraphicLabel() string { return t.size.Name() }
+func (t *TruncNode) label() string        { return "Trunc" }
+
+func (t *TruncNode) compute() (types.Type, error) { return computeExtend(t.size, t.Value()), nil }
+func (t *TruncNode) idealize() (Node, error)      { return idealizeExtend(t, t.size) }
+
+func (t *TruncNode) toStringInternal(sb *strings.Builder) { extendToString(t.size, t.Value(), sb) }
+
+// computeExtend folds constants by wrapping them at the width of size. Any other value fits in size once extended.
+func computeExtend(size *types.Int, value Node) types.Type {
+	if typ, ok := Type(value).(*types.Int); ok {
+		if typ.Constant() {
+			return types.NewInt(size.Wrap(typ.Value))
+		}
+		if typ.Top() {
+			return typ
+		}
+	}
+	return size
+}
+
+// idealizeExtend removes the extension n of a value that already fits in size. An extension of a value that was extended from at least as many bits only depends on the low bits, which the inner extension left unchanged, so the inner one is skipped.
+func idealizeExtend(n Node, size *types.Int) (Node, error) {
+	value := In(n, 0)
+	if typ, ok := Type(value).(*types.Int); ok && size.Contains(typ) {
+		return value, nil
+	}
+
+	var inner *types.Int
+	switch v := value.(type) {
+	case *SignExtNode:
+		inner = v.size
+	case *TruncNode:
+		inner = v.size
+	default:
+		return nil, nil
+	}
+	if inner.Bits() < size.Bits() {
+		return nil, nil
+	}
+	return n, setIn(n, 0, In(value, 0))
+}
+
+func extendToString(size *types.Int, value Node, sb *strings.Builder) {
+	sb.WriteString(size.Name())
+	sb.WriteString("(")
+	toString(value, sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,43 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// FunNode is the start of a function. Its result is a tuple of the control, the parameters and the initial memory of the function.
+type FunNode struct {
+	baseNode
+	Name       string
+	Params     []string
+	ParamTypes []types.Type
+	RetType    types.Type
+	mems       []types.Type
+
+	// Ret is the single return of the function, nil until the function body is generated or if the function never returns.
+	Ret *ReturnNode
+}
+
+func NewFunNode(name string, params []string, paramTypes []types.Type, ret types.Type, mems []types.Type) *FunNode {
+	return initBaseNode(&FunNode{Name: name, Params: params, ParamTypes: paramTypes, RetType: ret, mems: mems}, StartNode)
+}
+
+func (f *FunNode) IsControl() bool      { return true }
+func (f *FunNode) GraphicLabel() string { return f.Name }
+func (f *FunNode) label() string        { return "Fun_" + f.Name }
+
+func (f *FunNode) multinode() {}
+
+func (f *FunNode) compute() (types.Type, error) {
+	typs := []types.Type{types.Control}
+	typs = append(typs, f.ParamTypes...)
+	typs = append(typs, f.mems...)
+	return types.NewTuple(typs...), nil
+}
+
+func (f *FunNode) idealize() (Node, error) { return nil, nil }
+
+func (f *FunNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString(f.Name)
+}
@@ -2,84 +2,141 @@ package ir
 
 import (
 	"fmt"
-	"go/ast"
 	"go/token"
+	"slices"
 	"strconv"
 
 	"github.com/pkg/errors"
 
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
 )
 
-type instruction struct {
-	ast.Stmt
-	id string
-}
-
-// ShowGraphInst instructs the compiler to print the graph state
-var ShowGraphInst = &instruction{id: "showGraph"}
+// JumpTables lowers switches with dense case values to a JumpTableNode instead of a chain of comparisons.
+var JumpTables = false
 
-// DisablePeepholeInst instructs the compiler to disable peephole optimizations
-var DisablePeepholeInst = &instruction{id: "disablePeephole"}
+// ZeroInitialize initializes ints, flts and bools declared without a value to zero, instead of requiring an assignment before they are read.
+var ZeroInitialize = false
 
 type ASTError struct {
 	error
 	Pos token.Pos
 }
 
-func astError(pos token.Pos, n ast.Node) *ASTError {
+func astError(n ast.Node) *ASTError {
 	internal := errors.Errorf("Unsupported AST: %#v", n)
-	return &ASTError{error: internal, Pos: pos}
+	return &ASTError{error: internal, Pos: n.Pos()}
 }
 
 type Generator struct {
 	Scope *ScopeNode
+
+	// Scopes that breaks and continues jump to: the exit of the innermost loop or switch, and the head of the innermost loop. They are nil until the first jump.
+	breakScope    *ScopeNode
+	continueScope *ScopeNode
+	// The number of nested scopes at the jump targets, which are left when jumping. They are 0 outside of loops and switches.
+	breakDepth    int
+	continueDepth int
+
+	// Every return merges its control and value into these. They are turned into a single ReturnNode once generation is done.
+	retRegion *RegionNode
+	retPhi    *PhiNode
+	// retType is the return type of the function being generated, nil for a main module without a declared result, which may return an int or a flt
+	retType types.Type
+	// endless is the first loop of the function being generated that never exits, nil if there is none
+	endless *ast.ForStmt
+
+	// Functions are all the functions of the compilation unit, in declaration order. funcs are the functions visible in the module being generated.
+	Functions []*FunNode
+	funcs     map[string]*FunNode
+	funcDecls map[*ast.FuncDecl]*FunNode
+	// callEnds are linked to the returns of the called functions once all functions are generated
+	callEnds []*CallEndNode
+
+	// structs are the structs visible in the module being generated by name. fields are the fields of all structs, indexed by alias class - 1.
+	structs     map[string]*types.Struct
+	structDecls map[*ast.StructDecl]*types.Struct
+	fields      []*types.Field
+	// array is the layout of int arrays, nil if the compilation unit does not use arrays
+	array *types.Struct
+	// bytes is the layout of u8 arrays and string literals, nil if the compilation unit does not use them
+	bytes *types.Struct
+
+	// imports are the import declarations of the generated modules
+	imports map[*ast.ImportDecl]bool
 }
 
 func NewGenerator(arg types.Type) *Generator {
+	nodeID = 0
 	StartNode = newStartNode(types.NewTuple(types.Control, arg))
-	return &Generator{Scope: NewScopeNode()}
+	return &Generator{Scope: NewScopeNode(), funcDecls: map[*ast.FuncDecl]*FunNode{}, structDecls: map[*ast.StructDecl]*types.Struct{}, imports: map[*ast.ImportDecl]bool{}}
 }
 
-func (g *Generator) Generate(n ast.Node) (*ReturnNode, error) {
-	var retNode *ReturnNode
-	var err error
-	ast.Inspect(n, func(n ast.Node) bool {
-		if err != nil {
-			return false
-		}
+// Generate generates a program without imports.
+func (g *Generator) Generate(b *ast.BlockStmt) (*ReturnNode, error) {
+	return g.GenerateModule(&Module{Body: b})
+}
 
-		// New scope for the initial control and arguments
-		g.Scope.Push()
-		defer g.Scope.Pop()
-		var control Node
-		control, err = peephole(NewProjNode(StartNode, 0, Control))
-		if err != nil {
-			return false
+// GenerateModule generates the program of the main module, with the functions and structs of every module it imports directly or indirectly.
+func (g *Generator) GenerateModule(main *Module) (ret *ReturnNode, err error) {
+	modules := main.dependencies()
+	for _, m := range modules[:len(modules)-1] {
+		if err := checkModule(m); err != nil {
+			return nil, err
 		}
-		g.Scope.Define(Control, control)
-		var arg0 Node
-		arg0, err = peephole(NewProjNode(StartNode, 1, Arg0))
-		if err != nil {
-			return false
+	}
+	// New scope for the initial control and arguments
+	g.Scope.Push()
+	defer func() {
+		if err == nil {
+			err = g.Scope.Pop()
 		}
-		g.Scope.Define(Arg0, arg0)
+	}()
+	control, err := peephole(NewProjNode(StartNode, 0, Control))
+	if err != nil {
+		return nil, err
+	}
+	g.Scope.Define(Control, types.Control, control)
+	arg0, err := peephole(NewProjNode(StartNode, 1, Arg0))
+	if err != nil {
+		return nil, err
+	}
+	g.Scope.Define(Arg0, types.IntBottom, arg0)
 
-		if block, ok := n.(*ast.BlockStmt); ok {
-			var res Node
-			res, err = g.generateBlock(block)
-			retNode, _ = res.(*ReturnNode)
-			return false
+	if err := g.declareStructs(modules); err != nil {
+		return nil, err
+	}
+	if main.Result != nil {
+		g.retType, err = g.resolveType(main.Result)
+		if err != nil {
+			return nil, err
 		}
-
-		return true
-	})
-	return retNode, err
+	}
+	StartNode.addArgs(g.memTypes()...)
+	if err := g.defineMemory(StartNode, 2); err != nil {
+		return nil, err
+	}
+	if err := g.generateFunctions(modules); err != nil {
+		return nil, err
+	}
+	g.enter(main)
+	if _, err := g.generateBlock(main.Body); err != nil {
+		return nil, err
+	}
+	ret, err = g.generateReturnNode()
+	if err != nil {
+		return nil, err
+	}
+	// Functions may never return, but a program must
+	if ret == nil && g.endless != nil {
+		return nil, computeError(g.endless, "loop never exits, so the program never returns")
+	}
+	return ret, g.link()
 }
 
 func (g *Generator) generateBlock(b *ast.BlockStmt) (Node, error) {
+	// Generating control flow may replace g.Scope, so it is popped explicitly rather than deferred
 	g.Scope.Push()
-	defer g.Scope.Pop()
 
 	var res Node
 	for _, stmt := range b.List {
@@ -92,145 +149,2075 @@ func (g *Generator) generateBlock(b *ast.BlockStmt) (Node, error) {
 		}
 	}
 
-	return res, nil
+	return res, g.Scope.Pop()
 }
 
 func (g *Generator) generateStatement(s ast.Stmt) (Node, error) {
 	switch t := s.(type) {
 	case *ast.ReturnStmt:
 		return g.generateReturn(t)
-	case *ast.DeclStmt:
-		spec, ok := t.Decl.(*ast.GenDecl).Specs[0].(*ast.ValueSpec)
-		if !ok {
-			return nil, astError(s.Pos(), s)
+	case *ast.VarDecl:
+		return g.generateDecl(t)
+	case *ast.FuncDecl:
+		// Functions are generated before the statements of the top level block
+		if _, ok := g.funcDecls[t]; !ok {
+			return nil, computeError(t, "functions can only be declared at the top level")
+		}
+		return nil, nil
+	case *ast.StructDecl:
+		// Structs are declared before the statements of the top level block
+		if _, ok := g.structDecls[t]; !ok {
+			return nil, computeError(t, "structs can only be declared at the top level")
+		}
+		return nil, nil
+	case *ast.ImportDecl:
+		// Modules are imported before the statements of the top level block
+		if !g.imports[t] {
+			return nil, computeError(t, "imports can only be declared at the top level")
 		}
-		return g.generateDecl(spec)
+		return nil, nil
 	case *ast.BlockStmt:
 		return g.generateBlock(t)
 	case *ast.AssignStmt:
 		return g.generateAssign(t)
-	case *instruction:
-		switch s {
-		case ShowGraphInst:
+	case *ast.IncDecStmt:
+		return g.generateIncDec(t)
+	case *ast.IfStmt:
+		return g.generateIf(t)
+	case *ast.ForStmt:
+		return g.generateFor(t)
+	case *ast.BranchStmt:
+		return g.generateBranch(t)
+	case *ast.SwitchStmt:
+		return g.generateSwitch(t)
+	case *ast.InstructionStmt:
+		switch t.Name.Name {
+		case "showGraph":
 			fmt.Println(Visualize(g))
-		case DisablePeepholeInst:
+		case "disablePeephole":
 			DisablePeephole = true
+		default:
+			return nil, computeError(t.Name, "unknown compiler instruction")
 		}
 		return nil, nil
 	}
-	return nil, astError(s.Pos(), s)
+	return nil, astError(s)
 }
 
-func (g *Generator) generateAssign(a *ast.AssignStmt) (Node, error) {
-	id, ok := a.Lhs[0].(*ast.Ident)
+// declareStructs declares all the structs declared at the top level of the modules, and the layouts of the arrays that are used. Every field gets its own alias class.
+func (g *Generator) declareStructs(modules []*Module) error {
+	ints, bytes := false, false
+	for _, m := range modules {
+		i, b := usesArrays(m.Body)
+		ints, bytes = ints || i, bytes || b
+	}
+	if ints {
+		g.array = types.NewArray(types.IntBottom, len(g.fields)+1, len(g.fields)+2)
+		g.fields = append(g.fields, g.array.Fields...)
+	}
+	if bytes {
+		g.bytes = types.NewArray(types.U8, len(g.fields)+1, len(g.fields)+2)
+		g.fields = append(g.fields, g.bytes.Fields...)
+	}
+
+	// Modules come after the modules they import, so the imported structs are declared first. Imports and declarations are handled in source order, so a name defined twice is reported at the later one.
+	for _, m := range modules {
+		g.enter(m)
+		for _, stmt := range m.Body.List {
+			var err error
+			switch d := stmt.(type) {
+			case *ast.ImportDecl:
+				err = g.importStructs(m, d)
+			case *ast.StructDecl:
+				err = g.declareStruct(d)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) declareStruct(d *ast.StructDecl) error {
+	name := d.Name.Name
+	if _, ok := g.structs[name]; ok {
+		return computeError(d.Name, "struct already defined: "+name)
+	}
+	if _, ok := primitiveType(d.Name); ok {
+		return computeError(d.Name, "cannot redefine "+name)
+	}
+
+	var fields []*types.Field
+	for _, field := range d.Fields {
+		typ, ok := primitiveType(field.Type)
+		if !ok {
+			return computeError(field.Type, "fields must be int, flt or bool")
+		}
+		id := field.Name
+		for _, f := range fields {
+			if f.Name == id.Name {
+				return computeError(id, "field already defined: "+id.Name)
+			}
+		}
+		f := &types.Field{Name: id.Name, Type: typ, Alias: len(g.fields) + 1}
+		fields = append(fields, f)
+		g.fields = append(g.fields, f)
+	}
+
+	s := types.NewStruct(name, fields)
+	g.structs[name] = s
+	g.structDecls[d] = s
+	return nil
+}
+
+// usesArrays returns whether int arrays and u8 arrays are used anywhere in the block, by an array type, an array allocation or a string literal. Arrays of other types are counted as int arrays, and reported when they are generated.
+func usesArrays(b *ast.BlockStmt) (ints bool, bytes bool) {
+	use := func(elt *ast.Ident) {
+		if elt.Name == "u8" {
+			bytes = true
+		} else {
+			ints = true
+		}
+	}
+	ast.Inspect(b, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ArrayType:
+			use(n.Elt)
+		case *ast.NewExpr:
+			if n.Len != nil {
+				use(n.Type)
+			}
+		case *ast.BasicLit:
+			bytes = bytes || n.Kind == token.STRING
+		}
+		return true
+	})
+	return ints, bytes
+}
+
+// memTypes returns the initial memory of every alias class.
+func (g *Generator) memTypes() []types.Type {
+	var typs []types.Type
+	for _, f := range g.fields {
+		typs = append(typs, types.NewMem(f.Alias))
+	}
+	return typs
+}
+
+// defineMemory defines the memory of every alias class in the current scope, projected from start starting at index first.
+func (g *Generator) defineMemory(start MultiNode, first int) error {
+	for i, f := range g.fields {
+		name := memName(f.Alias)
+		mem, err := peephole(NewProjNode(start, first+i, name))
+		if err != nil {
+			return err
+		}
+		err = g.Scope.Define(name, types.NewMem(f.Alias), mem)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memName is the name of the memory of an alias class in the scope.
+func memName(alias int) string { return "$" + strconv.Itoa(alias) }
+
+// resolveType returns the type of a declaration with the given type name.
+func (g *Generator) resolveType(e ast.TypeExpr) (types.Type, error) {
+	if n, ok := e.(*ast.NullableType); ok {
+		typ, err := g.resolveType(n.X)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := typ.(*types.MemPtr)
+		if !ok {
+			return nil, computeError(n.X, "only references can be nullable, got "+typeName(typ))
+		}
+		return p.OrNull(), nil
+	}
+	if a, ok := e.(*ast.ArrayType); ok {
+		array, err := g.arrayOf(a.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return array.Ptr(), nil
+	}
+	if typ, ok := primitiveType(e); ok {
+		return typ, nil
+	}
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return nil, astError(e)
+	}
+	if s, ok := g.structs[id.Name]; ok {
+		return s.Ptr(), nil
+	}
+	return nil, computeError(id, "unknown type "+id.Name)
+}
+
+// sizedInts are the sized int type names. Ints are 64 bits wide, so i64 is the same as int.
+var sizedInts = map[string]*types.Int{
+	"i8": types.I8, "i16": types.I16, "i32": types.I32, "i64": types.IntBottom,
+	"u8": types.U8, "u16": types.U16, "u32": types.U32,
+}
+
+// primitiveType returns the type of the int, sized int, flt and bool type names.
+func primitiveType(e ast.TypeExpr) (types.Type, bool) {
+	id, ok := e.(*ast.Ident)
 	if !ok {
-		return nil, astError(a.Pos(), a)
+		return nil, false
+	}
+	if s, ok := sizedInts[id.Name]; ok {
+		return s, true
+	}
+	switch id.Name {
+	case "int":
+		return types.IntBottom, true
+	case "flt":
+		return types.FltBottom, true
+	case "bool":
+		return types.BoolBottom, true
+	}
+	return nil, false
+}
+
+// checkType returns an error if the node cannot be used where the declared type is expected. References must be to the same struct, can only be null if the declared reference is nullable, and cannot be mixed with numbers.
+func checkType(declared types.Type, n Node, e ast.Expr) error {
+	if d, ok := declared.(*types.MemPtr); ok {
+		if p, ok := Type(n).(*types.MemPtr); ok && p.Isa(d) {
+			return nil
+		}
+	} else if typeName(declared) == typeName(Type(n)) {
+		return nil
 	}
-	expr, err := g.generateExpr(a.Rhs[0])
+	return computeError(e, fmt.Sprintf("expected %s, got %s", typeName(declared), typeName(Type(n))))
+}
+
+// coerce returns the node converted to the declared type. Ints are widened to floats and truncated to the width of sized ints, any other mismatch is an error.
+func coerce(declared types.Type, n Node, e ast.Expr) (Node, error) {
+	if typeName(declared) == "flt" && typeName(Type(n)) == "int" {
+		return peephole(NewToFltNode(n))
+	}
+	err := checkType(declared, n, e)
 	if err != nil {
 		return nil, err
 	}
+	if s, ok := declared.(*types.Int); ok && s.Sized() {
+		return peephole(NewExtendNode(s, n))
+	}
+	return n, nil
+}
 
-	exists, err := g.Scope.Update(id.Name, expr)
-	if !exists {
-		return nil, computeError(id, "unknown identifier")
+// checkNumber returns an error if the node is not an int or a flt.
+func checkNumber(n Node, e ast.Expr) error {
+	if !isNumber(n) {
+		return computeError(e, "expected int or flt, got "+typeName(Type(n)))
+	}
+	return nil
+}
+
+// checkCondition returns an error if the node cannot be used as a condition. Ints are true when they are not zero, and references when they are not null.
+func checkCondition(n Node, e ast.Expr) error {
+	if _, ok := Type(n).(*types.MemPtr); ok {
+		return nil
+	}
+	if name := typeName(Type(n)); name != "int" && name != "bool" {
+		return computeError(e, "expected bool or int, got "+name)
+	}
+	return nil
+}
+
+// checkPrimitive returns an error if the node is a reference.
+func checkPrimitive(n Node, e ast.Expr) error {
+	if _, ok := Type(n).(*types.MemPtr); ok {
+		return computeError(e, "expected int, flt or bool, got "+typeName(Type(n)))
+	}
+	return nil
+}
+
+func isNumber(n Node) bool {
+	name := typeName(Type(n))
+	return name == "int" || name == "flt"
+}
+
+// typeName returns the name of the type in Simple. Types that are not known to be a float, a bool or a reference are ints.
+func typeName(t types.Type) string {
+	switch t := t.(type) {
+	case *types.MemPtr:
+		if t.Struct == nil {
+			return "null"
+		}
+		if t.Nullable {
+			return t.Struct.Name + "?"
+		}
+		return t.Struct.Name
+	case *types.Flt:
+		return "flt"
+	case *types.Bool:
+		return "bool"
+	}
+	return "int"
+}
+
+// zeroValue returns the initial value of fields of the type.
+func zeroValue(t types.Type) types.Type {
+	switch typeName(t) {
+	case "flt":
+		return types.NewFlt(0)
+	case "bool":
+		return types.False
+	}
+	return types.NewInt(0)
+}
+
+// generateFunctions declares and generates the functions declared at the top level of the modules. Modules come after the modules they import, so the imported functions are declared first.
+func (g *Generator) generateFunctions(modules []*Module) error {
+	for _, m := range modules {
+		g.enter(m)
+		// Imports and declarations are handled in source order, so a name defined twice is reported at the later one
+		var decls []*ast.FuncDecl
+		for _, stmt := range m.Body.List {
+			var err error
+			switch d := stmt.(type) {
+			case *ast.ImportDecl:
+				err = g.importFuncs(m, d)
+			case *ast.FuncDecl:
+				decls = append(decls, d)
+				err = g.declareFunction(d)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, decl := range decls {
+			err := g.generateFunction(decl, g.funcDecls[decl])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) declareFunction(decl *ast.FuncDecl) error {
+	name := decl.Name.Name
+	if _, ok := g.funcs[name]; ok {
+		return computeError(decl.Name, "function already defined: "+name)
+	}
+	ret, ok := primitiveType(decl.Result)
+	if !ok {
+		return computeError(decl.Name, "functions must return int, flt or bool")
+	}
+
+	var params []string
+	var paramTypes []types.Type
+	for _, param := range decl.Params {
+		typ, ok := primitiveType(param.Type)
+		if !ok {
+			return computeError(param.Type, "parameters must be int, flt or bool")
+		}
+		params = append(params, param.Name.Name)
+		paramTypes = append(paramTypes, typ)
 	}
+
+	n, err := peephole(NewFunNode(name, params, paramTypes, ret, g.memTypes()))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return expr, nil
+	fun := n.(*FunNode)
+	g.Functions = append(g.Functions, fun)
+	g.funcs[name] = fun
+	g.funcDecls[decl] = fun
+	return nil
+}
+
+// arrayOf returns the layout of the arrays of the element type.
+func (g *Generator) arrayOf(elt *ast.Ident) (*types.Struct, error) {
+	switch elt.Name {
+	case "int":
+		return g.array, nil
+	case "u8":
+		return g.bytes, nil
+	}
+	return nil, computeError(elt, "arrays must be of int or u8")
 }
 
-func (g *Generator) generateDecl(v *ast.ValueSpec) (Node, error) {
-	name := v.Names[0].Name
-	value, err := g.generateExpr(v.Values[0])
+func (g *Generator) generateFunction(decl *ast.FuncDecl, fun *FunNode) error {
+	// Functions do not see the names of the top level, so they are generated with fresh state
+	scope, breakScope, continueScope, retRegion, retPhi, retType := g.Scope, g.breakScope, g.continueScope, g.retRegion, g.retPhi, g.retType
+	breakDepth, continueDepth, endless := g.breakDepth, g.continueDepth, g.endless
+	defer func() {
+		g.Scope, g.breakScope, g.continueScope, g.retRegion, g.retPhi, g.retType = scope, breakScope, continueScope, retRegion, retPhi, retType
+		g.breakDepth, g.continueDepth, g.endless = breakDepth, continueDepth, endless
+	}()
+	g.Scope, g.breakScope, g.continueScope, g.retRegion, g.retPhi, g.retType = NewScopeNode(), nil, nil, nil, nil, fun.RetType
+	g.breakDepth, g.continueDepth, g.endless = 0, 0, nil
+
+	g.Scope.Push()
+	control, err := peephole(NewProjNode(fun, 0, Control))
 	if err != nil {
-		return nil, err
+		return err
+	}
+	g.Scope.Define(Control, types.Control, control)
+	for i, p := range decl.Params {
+		param, err := peephole(NewProjNode(fun, i+1, p.Name.Name))
+		if err != nil {
+			return err
+		}
+		err = g.Scope.Define(p.Name.Name, fun.ParamTypes[i], param)
+		if err != nil {
+			return computeError(p.Name, err.Error())
+		}
 	}
-	err = g.Scope.Define(name, value)
+	// Functions cannot see the structs of their callers, so they start with their own memory
+	err = g.defineMemory(fun, len(decl.Params)+1)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return value, nil
-}
 
-func (g *Generator) generateReturn(r *ast.ReturnStmt) (*ReturnNode, error) {
-	expr, err := g.generateExpr(r.Results[0])
+	_, err = g.generateBlock(decl.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	n, err := peephole(NewReturnNode(g.Scope.Control(), expr))
+	if g.Scope.Control() != nil {
+		return computeErrorAt(decl.Body.Rbrace, "missing return in function "+fun.Name)
+	}
+	fun.Ret, err = g.generateReturnNode()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	g.Scope.SetControl(nil)
-	return n.(*ReturnNode), nil
+
+	err = g.Scope.Pop()
+	if err != nil {
+		return err
+	}
+	return kill(g.Scope)
 }
 
-func (g *Generator) generateExpr(e ast.Expr) (Node, error) {
-	switch t := e.(type) {
-	case *ast.BinaryExpr:
-		lhs, err := g.generateExpr(t.X)
+// link links all calls to the returns of the functions they call.
+func (g *Generator) link() error {
+	for _, c := range g.callEnds {
+		if dead(c) {
+			continue
+		}
+		err := c.link()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rhs, err := g.generateExpr(t.Y)
+	}
+	return nil
+}
+
+func (g *Generator) generateCall(c *ast.CallExpr) (Node, error) {
+	id := c.Fun
+	if _, ok := sizedInts[id.Name]; ok || id.Name == "int" || id.Name == "flt" {
+		return g.generateConversion(id, c)
+	}
+	fun, ok := g.funcs[id.Name]
+	if !ok {
+		return nil, computeError(id, "unknown function")
+	}
+	if len(c.Args) != len(fun.Params) {
+		return nil, computeError(c, fmt.Sprintf("%s expects %d arguments, got %d", fun.Name, len(fun.Params), len(c.Args)))
+	}
+
+	var args []Node
+	for i, arg := range c.Args {
+		n, err := g.generateExpr(arg)
 		if err != nil {
 			return nil, err
 		}
-		switch t.Op {
-		case token.ADD:
-			return peephole(NewAddNode(lhs, rhs))
-		case token.SUB:
-			return peephole(NewSubNode(lhs, rhs))
-		case token.MUL:
-			return peephole(NewMulNode(lhs, rhs))
-		case token.QUO:
-			return peephole(NewDivNode(lhs, rhs))
-		case token.EQL:
-			return peephole(NewBoolNode(lhs, EQ, rhs))
-		case token.GEQ:
-			lhs, rhs = rhs, lhs
-			fallthrough
-		case token.LEQ:
-			return peephole(NewBoolNode(lhs, LE, rhs))
-		case token.GTR:
-			lhs, rhs = rhs, lhs
-			fallthrough
-		case token.LSS:
-			return peephole(NewBoolNode(lhs, LT, rhs))
-		case token.NEQ:
-			eq, err := peephole(NewBoolNode(lhs, EQ, rhs))
-			if err != nil {
-				return nil, err
-			}
-			return peephole(NewNotNode(eq))
-		}
-	case *ast.ParenExpr:
-		return g.generateExpr(t.X)
-	case *ast.UnaryExpr:
-		value, err := g.generateExpr(t.X)
+		n, err = coerce(fun.ParamTypes[i], n, arg)
 		if err != nil {
 			return nil, err
 		}
-		switch t.Op {
-		case token.SUB:
-			return peephole(NewMinusNode(value))
-		case token.NOT:
-			return peephole(NewNotNode(value))
-		}
-	case *ast.BasicLit:
-		num, err := strconv.Atoi(t.Value)
+		args = append(args, n)
+	}
+
+	call, err := peephole(NewCallNode(g.Scope.Control(), fun, args...))
+	if err != nil {
+		return nil, err
+	}
+	n, err := peephole(NewCallEndNode(call.(*CallNode)))
+	if err != nil {
+		return nil, err
+	}
+	callEnd := n.(*CallEndNode)
+	g.callEnds = append(g.callEnds, callEnd)
+
+	// Keep the call end alive until both projections exist
+	pin(callEnd)
+	control, err := g.branchControl(NewProjNode(callEnd, 0, Control))
+	if err != nil {
+		return nil, err
+	}
+	value, err := peephole(NewProjNode(callEnd, 1, "$ret"))
+	if err != nil {
+		return nil, err
+	}
+	unpin(callEnd)
+	return value, g.Scope.SetControl(control)
+}
+
+// generateConversion converts the argument of a call to int, a sized int or flt. Converting a flt to an int truncates it, and converting to a sized int wraps at its width.
+func (g *Generator) generateConversion(id *ast.Ident, c *ast.CallExpr) (Node, error) {
+	if len(c.Args) != 1 {
+		return nil, computeError(c, id.Name+" expects 1 argument")
+	}
+	value, err := g.generateExpr(c.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	err = checkNumber(value, c.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	if id.Name == "flt" {
+		return coerce(types.FltBottom, value, c.Args[0])
+	}
+	if typeName(Type(value)) == "flt" {
+		value, err = peephole(NewToIntNode(value))
 		if err != nil {
 			return nil, err
 		}
-		return peephole(NewConstantNode(types.NewInt(num)))
-	case *ast.Ident:
-		n, ok := g.Scope.Lookup(t.Name)
-		if !ok {
-			return nil, computeError(e, "unknown identifier")
-		}
-		return n, nil
 	}
-	return nil, astError(e.Pos(), e)
+	if s, ok := sizedInts[id.Name]; ok {
+		return coerce(s, value, c.Args[0])
+	}
+	return value, nil
+}
+
+// compoundOps maps compound assignments to the operation applied to the assigned location.
+var compoundOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN: token.ADD,
+	token.SUB_ASSIGN: token.SUB,
+	token.MUL_ASSIGN: token.MUL,
+	token.QUO_ASSIGN: token.QUO,
+}
+
+func (g *Generator) generateAssign(a *ast.AssignStmt) (Node, error) {
+	// x op= y is generated as x = x op y
+	if op, ok := compoundOps[a.Tok]; ok {
+		rhs := &ast.BinaryExpr{X: a.Lhs, OpPos: a.TokPos, Op: op, Y: a.Rhs}
+		a = &ast.AssignStmt{Lhs: a.Lhs, TokPos: a.TokPos, Tok: token.ASSIGN, Rhs: rhs}
+	}
+	switch lhs := a.Lhs.(type) {
+	case *ast.FieldExpr:
+		return g.generateStore(lhs, a.Rhs)
+	case *ast.LenExpr:
+		return nil, computeErrorAt(lhs.Hash, "cannot assign to the length of an array")
+	case *ast.IndexExpr:
+		return g.generateIndexStore(lhs, a.Rhs)
+	}
+	id, ok := a.Lhs.(*ast.Ident)
+	if !ok {
+		return nil, astError(a)
+	}
+	expr, err := g.generateExpr(a.Rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	declared, exists := g.Scope.DeclaredType(id.Name)
+	if !exists {
+		return nil, g.unknownIdentifier(id)
+	}
+	if g.Scope.Final(id.Name) {
+		return nil, computeError(id, "cannot assign to val "+id.Name)
+	}
+	expr, err = coerce(declared, expr, a.Rhs)
+	if err != nil {
+		return nil, err
+	}
+	_, err = g.Scope.Update(id.Name, expr)
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// generateIncDec generates x++ and x-- as x += 1 and x -= 1.
+func (g *Generator) generateIncDec(s *ast.IncDecStmt) (Node, error) {
+	tok := token.ADD_ASSIGN
+	if s.Tok == token.DEC {
+		tok = token.SUB_ASSIGN
+	}
+	one := &ast.BasicLit{ValuePos: s.TokPos, Kind: token.INT, Value: "1"}
+	return g.generateAssign(&ast.AssignStmt{Lhs: s.X, TokPos: s.TokPos, Tok: tok, Rhs: one})
+}
+
+// generateDecl defines a name. Without a type name the type is inferred from the value. Final names are declared with val and cannot be assigned again.
+func (g *Generator) generateDecl(v *ast.VarDecl) (Node, error) {
+	name := v.Name.Name
+	var declared types.Type
+	if v.Type != nil {
+		var err error
+		declared, err = g.resolveType(v.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v.Value == nil {
+		return g.generateUninit(name, declared, v)
+	}
+
+	value, err := g.generateExpr(v.Value)
+	if err != nil {
+		return nil, err
+	}
+	if declared == nil {
+		if Type(value) == types.Null {
+			return nil, computeError(v.Value, "cannot infer the type of null")
+		}
+		declared = inferType(Type(value))
+	}
+	value, err = coerce(declared, value, v.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Final {
+		err = g.Scope.DefineFinal(name, declared, value)
+	} else {
+		err = g.Scope.Define(name, declared, value)
+	}
+	if err != nil {
+		return nil, computeError(v.Name, err.Error())
+	}
+	return value, nil
+}
+
+// generateUninit defines a name declared without a value. With ZeroInitialize, ints, flts and bools start at zero and nullable references start as null. Otherwise, and for non-null references which have no zero value, the name must be assigned on every path before it is read.
+func (g *Generator) generateUninit(name string, declared types.Type, v *ast.VarDecl) (Node, error) {
+	if declared == nil {
+		return nil, computeError(v.Name, "missing type or value for "+name)
+	}
+	// Non-null references have no zero value, so their placeholder is a constant of the declared type
+	placeholder := declared
+	if p, ok := declared.(*types.MemPtr); !ok {
+		placeholder = zeroValue(declared)
+	} else if p.Nullable {
+		placeholder = types.Null
+	}
+	value, err := peephole(NewConstantNode(placeholder))
+	if err != nil {
+		return nil, err
+	}
+	if ZeroInitialize && placeholder != declared {
+		err = g.Scope.Define(name, declared, value)
+	} else {
+		err = g.Scope.DefineUninit(name, declared, value)
+	}
+	if err != nil {
+		return nil, computeError(v.Name, err.Error())
+	}
+	return value, nil
+}
+
+// inferType returns the declared type of a name initialized with a value of type t. Constants are widened, so the name can be assigned other values of the same type.
+func inferType(t types.Type) types.Type {
+	switch typeName(t) {
+	case "flt":
+		return types.FltBottom
+	case "bool":
+		return types.BoolBottom
+	case "int":
+		return types.IntBottom
+	}
+	// String literals widen to u8 arrays
+	if p, ok := t.(*types.MemPtr); ok && p.Constant() {
+		return p.Struct.Ptr()
+	}
+	return t
+}
+
+// generateNew allocates a struct and initializes all of its fields to zero.
+func (g *Generator) generateNew(n *ast.NewExpr) (Node, error) {
+	if n.Len != nil {
+		return g.generateNewArray(n)
+	}
+	id := n.Type
+	s, ok := g.structs[id.Name]
+	if !ok {
+		return nil, computeError(id, "unknown struct "+id.Name)
+	}
+
+	ptr, err := peephole(NewNewNode(g.Scope.Control(), s.Ptr()))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range s.Fields {
+		zero, err := peephole(NewConstantNode(zeroValue(f.Type)))
+		if err != nil {
+			return nil, err
+		}
+		err = g.store(f, ptr, zero)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ptr, nil
+}
+
+// generateNewArray allocates an array with the given length and initializes all of its elements to zero.
+func (g *Generator) generateNewArray(a *ast.NewExpr) (Node, error) {
+	array, err := g.arrayOf(a.Type)
+	if err != nil {
+		return nil, err
+	}
+	length, err := g.generateExpr(a.Len)
+	if err != nil {
+		return nil, err
+	}
+	err = checkType(types.IntBottom, length, a.Len)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := Type(length).(*types.Int); ok && l.Constant() && l.Value < 0 && g.Scope.Control() != nil {
+		return nil, computeError(a.Len, fmt.Sprintf("negative array length %d", l.Value))
+	}
+
+	ptr, err := peephole(NewNewNode(g.Scope.Control(), array.Ptr()))
+	if err != nil {
+		return nil, err
+	}
+	err = g.store(array.Field(types.ArrayLength), ptr, length)
+	if err != nil {
+		return nil, err
+	}
+	zero, err := peephole(NewConstantNode(types.NewInt(0)))
+	if err != nil {
+		return nil, err
+	}
+	return ptr, g.store(array.Field(types.ArrayElems), ptr, zero)
+}
+
+// field returns the struct reference and the field that the field expression refers to.
+func (g *Generator) field(sel *ast.FieldExpr) (Node, *types.Field, error) {
+	ptr, err := g.generateExpr(sel.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, ok := Type(ptr).(*types.MemPtr)
+	if !ok || p.Struct == nil {
+		return nil, nil, computeError(sel.X, "expected a struct, got "+typeName(Type(ptr)))
+	}
+	if p.Nullable {
+		return nil, nil, computeError(sel.X, fmt.Sprintf("cannot access field %s of possibly null %s", sel.Field.Name, typeName(p)))
+	}
+	f := p.Struct.Field(sel.Field.Name)
+	if f == nil {
+		return nil, nil, computeError(sel.Field, fmt.Sprintf("unknown field %s in %s", sel.Field.Name, p.Struct.Name))
+	}
+	return ptr, f, nil
+}
+
+func (g *Generator) generateLoad(sel *ast.FieldExpr) (Node, error) {
+	ptr, f, err := g.field(sel)
+	if err != nil {
+		return nil, err
+	}
+	mem, _ := g.Scope.Lookup(memName(f.Alias))
+	return peephole(NewLoadNode(f, mem, ptr))
+}
+
+// generateLength loads the length of an array.
+func (g *Generator) generateLength(l *ast.LenExpr) (Node, error) {
+	ptr, err := g.generateExpr(l.X)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := Type(ptr).(*types.MemPtr)
+	if !ok || p.Struct == nil || !p.Struct.IsArray() {
+		return nil, computeError(l.X, "expected an array, got "+typeName(Type(ptr)))
+	}
+	if p.Nullable {
+		return nil, computeError(l.X, "cannot get the length of possibly null "+typeName(p))
+	}
+	f := p.Struct.Field(types.ArrayLength)
+	mem, _ := g.Scope.Lookup(memName(f.Alias))
+	return peephole(NewLoadNode(f, mem, ptr))
+}
+
+func (g *Generator) generateStore(sel *ast.FieldExpr, e ast.Expr) (Node, error) {
+	ptr, f, err := g.field(sel)
+	if err != nil {
+		return nil, err
+	}
+	value, err := g.generateExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	value, err = coerce(f.Type, value, e)
+	if err != nil {
+		return nil, err
+	}
+	return value, g.store(f, ptr, value)
+}
+
+// element returns the array reference, the field of the elements and the bounds checked index of an index expression.
+func (g *Generator) element(ix *ast.IndexExpr) (Node, *types.Field, Node, error) {
+	ptr, err := g.generateExpr(ix.X)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p, ok := Type(ptr).(*types.MemPtr)
+	if !ok || p.Struct == nil || !p.Struct.IsArray() {
+		return nil, nil, nil, computeError(ix.X, "expected an array, got "+typeName(Type(ptr)))
+	}
+	if p.Nullable {
+		return nil, nil, nil, computeError(ix.X, "cannot index possibly null "+typeName(p))
+	}
+	index, err := g.generateExpr(ix.Index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = checkType(types.IntBottom, index, ix.Index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Keep the reference alive while the length is loaded, since a folded length of a string literal drops its only use
+	lengthField := p.Struct.Field(types.ArrayLength)
+	mem, _ := g.Scope.Lookup(memName(lengthField.Alias))
+	pin(ptr)
+	length, err := peephole(NewLoadNode(lengthField, mem, ptr))
+	unpin(ptr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Indices in dead code are never used
+	if g.Scope.Control() != nil {
+		err = checkIndex(index, length, ix.Index)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	checked, err := peephole(NewBoundsCheckNode(g.Scope.Control(), index, length))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ptr, p.Struct.Field(types.ArrayElems), checked, nil
+}
+
+// checkIndex returns an error if a constant index is known to be out of bounds: when it is negative, or not below a constant length.
+func checkIndex(index Node, length Node, e ast.Expr) error {
+	i, ok := Type(index).(*types.Int)
+	if !ok || !i.Constant() {
+		return nil
+	}
+	if i.Value < 0 {
+		return computeError(e, fmt.Sprintf("negative index %d", i.Value))
+	}
+	if l, ok := Type(length).(*types.Int); ok && l.Constant() && i.Value >= l.Value {
+		return computeError(e, fmt.Sprintf("index %d out of bounds for length %d", i.Value, l.Value))
+	}
+	return nil
+}
+
+func (g *Generator) generateIndexLoad(ix *ast.IndexExpr) (Node, error) {
+	ptr, f, index, err := g.element(ix)
+	if err != nil {
+		return nil, err
+	}
+	mem, _ := g.Scope.Lookup(memName(f.Alias))
+	return peephole(NewArrayLoadNode(f, mem, ptr, index))
+}
+
+func (g *Generator) generateIndexStore(ix *ast.IndexExpr, e ast.Expr) (Node, error) {
+	ptr, f, index, err := g.element(ix)
+	if err != nil {
+		return nil, err
+	}
+	// Loads from string literals are folded, so their bytes cannot change
+	if Type(ptr).Constant() {
+		return nil, computeError(ix.X, "cannot modify a string literal")
+	}
+	// Keep the index alive while the value is generated
+	pin(index)
+	value, err := g.generateExpr(e)
+	unpin(index)
+	if err != nil {
+		return nil, err
+	}
+	value, err = coerce(f.Type, value, e)
+	if err != nil {
+		return nil, err
+	}
+
+	name := memName(f.Alias)
+	mem, _ := g.Scope.Lookup(name)
+	st, err := peephole(NewArrayStoreNode(g.Scope.Control(), f, mem, ptr, index, value))
+	if err != nil {
+		return nil, err
+	}
+	_, err = g.Scope.Update(name, st)
+	return value, err
+}
+
+// store stores the value in the field and updates the memory of its alias class.
+func (g *Generator) store(f *types.Field, ptr Node, value Node) error {
+	name := memName(f.Alias)
+	mem, _ := g.Scope.Lookup(name)
+	st, err := peephole(NewStoreNode(g.Scope.Control(), f, mem, ptr, value))
+	if err != nil {
+		return err
+	}
+	_, err = g.Scope.Update(name, st)
+	return err
+}
+
+func (g *Generator) generateIf(i *ast.IfStmt) (Node, error) {
+	pred, err := g.generateExpr(i.Cond)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCondition(pred, i.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	ifTrue, ifFalse, err := g.generateBranches(pred)
+	if err != nil {
+		return nil, err
+	}
+
+	falseScope := g.Scope.Dup()
+	err = g.Scope.SetControl(ifTrue)
+	if err != nil {
+		return nil, err
+	}
+	err = g.refineBranches(i.Cond, g.Scope, ifTrue, falseScope, ifFalse)
+	if err != nil {
+		return nil, err
+	}
+	_, err = g.generateBlock(i.Body)
+	if err != nil {
+		return nil, err
+	}
+	trueScope := g.Scope
+
+	g.Scope = falseScope
+	err = g.Scope.SetControl(ifFalse)
+	if err != nil {
+		return nil, err
+	}
+	if i.Else != nil {
+		_, err = g.generateStatement(i.Else)
+		if err != nil {
+			return nil, err
+		}
+	}
+	falseScope = g.Scope
+
+	g.Scope = trueScope
+	return trueScope.Merge(falseScope)
+}
+
+// nullCheck returns the name of the reference checked by a condition of the form p, !p, p != null or p == null, and whether the reference is not null when the condition is true.
+func nullCheck(cond ast.Expr) (*ast.Ident, bool, bool) {
+	switch c := cond.(type) {
+	case *ast.ParenExpr:
+		return nullCheck(c.X)
+	case *ast.Ident:
+		return c, true, true
+	case *ast.UnaryExpr:
+		if c.Op == token.NOT {
+			id, whenTrue, ok := nullCheck(c.X)
+			return id, !whenTrue, ok
+		}
+	case *ast.BinaryExpr:
+		if c.Op != token.EQL && c.Op != token.NEQ {
+			break
+		}
+		x, y := c.X, c.Y
+		if isNull(x) {
+			x, y = y, x
+		}
+		if id, ok := x.(*ast.Ident); ok && isNull(y) {
+			return id, c.Op == token.NEQ, true
+		}
+	}
+	return nil, false, false
+}
+
+func isNull(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == "null"
+}
+
+// refineBranches refines a reference checked against null by the condition in the scope of the branch where it is not null. Both operands of && are true on its true branch, and both operands of || are false on its false branch, so their checks refine that branch. A nil scope is not refined.
+func (g *Generator) refineBranches(cond ast.Expr, trueScope *ScopeNode, ifTrue Node, falseScope *ScopeNode, ifFalse Node) error {
+	for p, ok := cond.(*ast.ParenExpr); ok; p, ok = cond.(*ast.ParenExpr) {
+		cond = p.X
+	}
+	if b, ok := cond.(*ast.BinaryExpr); ok && (b.Op == token.LAND || b.Op == token.LOR) {
+		for _, e := range []ast.Expr{b.X, b.Y} {
+			var err error
+			if b.Op == token.LAND {
+				err = g.refineBranches(e, trueScope, ifTrue, nil, nil)
+			} else {
+				err = g.refineBranches(e, nil, nil, falseScope, ifFalse)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	id, whenTrue, isCheck := nullCheck(cond)
+	switch {
+	case !isCheck:
+		return nil
+	case whenTrue:
+		return g.refine(trueScope, id, ifTrue)
+	}
+	return g.refine(falseScope, id, ifFalse)
+}
+
+// refine casts a nullable reference to a non-null reference in the scope of the branch where it is not null. The struct is taken from the declared type, because a reference that is always null has none. Nothing is cast if the name is not a nullable reference.
+func (g *Generator) refine(scope *ScopeNode, id *ast.Ident, control Node) error {
+	if scope == nil {
+		return nil
+	}
+	n, ok := scope.Lookup(id.Name)
+	if !ok {
+		return nil
+	}
+	declared, _ := scope.DeclaredType(id.Name)
+	p, ok := declared.(*types.MemPtr)
+	if !ok || !p.Nullable || p.Struct == nil {
+		return nil
+	}
+	cast, err := peephole(NewCastNode(control, p.NonNull(), n))
+	if err != nil {
+		return err
+	}
+	return scope.Refine(id.Name, cast)
+}
+
+// generateFor generates a loop. The init statement is in its own scope around the loop, a missing condition is always true, and the post statement runs after the body and every continue.
+func (g *Generator) generateFor(f *ast.ForStmt) (Node, error) {
+	if f.Init == nil {
+		return g.generateWhile(f)
+	}
+	g.Scope.Push()
+	_, err := g.generateStatement(f.Init)
+	if err != nil {
+		return nil, err
+	}
+	n, err := g.generateWhile(f)
+	if err != nil {
+		return nil, err
+	}
+	return n, g.Scope.Pop()
+}
+
+func (g *Generator) generateWhile(f *ast.ForStmt) (Node, error) {
+	loop, err := peephole(NewLoopNode(g.Scope.Control()))
+	if err != nil {
+		return nil, err
+	}
+	err = g.Scope.SetControl(loop)
+	if err != nil {
+		return nil, err
+	}
+	head := g.Scope
+	g.Scope, err = head.DupLoop()
+	if err != nil {
+		return nil, err
+	}
+
+	var pred Node
+	if f.Cond == nil {
+		pred, err = peephole(NewConstantNode(types.True))
+	} else {
+		pred, err = g.generateExpr(f.Cond)
+		if err == nil {
+			err = checkCondition(pred, f.Cond)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ifTrue, ifFalse, err := g.generateBranches(pred)
+	if err != nil {
+		return nil, err
+	}
+
+	savedBreak, savedContinue, savedBreakDepth, savedContinueDepth := g.breakScope, g.continueScope, g.breakDepth, g.continueDepth
+	defer func() {
+		g.breakScope, g.continueScope, g.breakDepth, g.continueDepth = savedBreak, savedContinue, savedBreakDepth, savedContinueDepth
+	}()
+
+	// The exit scope collects the false branch and all breaks
+	g.breakScope = g.Scope.Dup()
+	g.continueScope = nil
+	g.breakDepth, g.continueDepth = len(g.Scope.Scopes), len(g.Scope.Scopes)
+	err = g.breakScope.SetControl(ifFalse)
+	if err != nil {
+		return nil, err
+	}
+	err = g.Scope.SetControl(ifTrue)
+	if err != nil {
+		return nil, err
+	}
+	if f.Cond != nil {
+		err = g.refineBranches(f.Cond, g.Scope, ifTrue, g.breakScope, ifFalse)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = g.generateBlock(f.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The end of the body continues to the loop head as well
+	if g.continueScope != nil {
+		g.continueScope, err = g.jumpTo(g.continueScope, g.continueDepth)
+		if err != nil {
+			return nil, err
+		}
+		err = kill(g.Scope)
+		if err != nil {
+			return nil, err
+		}
+		g.Scope = g.continueScope
+	}
+	if f.Post != nil {
+		_, err = g.generateStatement(f.Post)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = head.EndLoop(g.Scope)
+	if err != nil {
+		return nil, err
+	}
+	g.Scope = g.breakScope
+	if g.Scope.Control() == nil && g.endless == nil {
+		g.endless = f
+	}
+	return g.Scope.Control(), kill(head)
+}
+
+// generateSwitch generates a switch as a chain of comparisons, or as a jump table when JumpTables is set and the values are dense. Every case ends by jumping to the exit of the switch, like a break.
+func (g *Generator) generateSwitch(s *ast.SwitchStmt) (Node, error) {
+	tag, err := g.generateExpr(s.Tag)
+	if err != nil {
+		return nil, err
+	}
+	err = checkType(types.IntBottom, tag, s.Tag)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the tag alive while the case values and the comparisons are generated, since folded comparisons drop their use of it
+	pin(tag)
+	values, err := g.caseValues(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*ScopeNode
+	if JumpTables && dense(values) {
+		entries, err = g.generateJumpTable(tag, values)
+	} else {
+		entries, err = g.generateCaseChain(tag, values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	unpin(tag)
+	if Unused(tag) {
+		err = kill(tag)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	savedBreak, savedBreakDepth := g.breakScope, g.breakDepth
+	defer func() { g.breakScope, g.breakDepth = savedBreak, savedBreakDepth }()
+	g.breakScope, g.breakDepth = nil, len(g.Scope.Scopes)
+
+	// The scope left when no case matches runs the default
+	noMatch := g.Scope
+	for i, c := range s.Cases {
+		if c.List == nil {
+			continue
+		}
+		g.Scope = entries[i]
+		_, err = g.generateBlock(c.Body)
+		if err != nil {
+			return nil, err
+		}
+		g.breakScope, err = g.jumpTo(g.breakScope, g.breakDepth)
+		if err != nil {
+			return nil, err
+		}
+		err = kill(g.Scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.Scope = noMatch
+	for _, c := range s.Cases {
+		if c.List == nil {
+			_, err = g.generateBlock(c.Body)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	g.breakScope, err = g.jumpTo(g.breakScope, g.breakDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every case returned, so the code after the switch is not reachable
+	if g.breakScope == nil {
+		return nil, nil
+	}
+	err = kill(g.Scope)
+	if err != nil {
+		return nil, err
+	}
+	g.Scope = g.breakScope
+	return g.Scope.Control(), nil
+}
+
+// caseValues returns the values of every case of the switch, which must be distinct int constants. The values of the default are nil.
+func (g *Generator) caseValues(s *ast.SwitchStmt) ([][]int, error) {
+	values := make([][]int, len(s.Cases))
+	seen := map[int]bool{}
+	for i, c := range s.Cases {
+		for _, e := range c.List {
+			n, err := g.generateExpr(e)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := Type(n).(*types.Int)
+			if !ok || !v.Constant() {
+				return nil, computeError(e, "case values must be int constants")
+			}
+			if seen[v.Value] {
+				return nil, computeError(e, fmt.Sprintf("duplicate case %d", v.Value))
+			}
+			seen[v.Value] = true
+			values[i] = append(values[i], v.Value)
+			if Unused(n) {
+				err = kill(n)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return values, nil
+}
+
+// dense returns true if a jump table for the values would have at least half of its entries used. Small switches are left to comparisons.
+func dense(values [][]int) bool {
+	var all []int
+	for _, v := range values {
+		all = append(all, v...)
+	}
+	if len(all) < 3 {
+		return false
+	}
+	lo, hi := slices.Min(all), slices.Max(all)
+	return hi-lo+1 <= 2*len(all)
+}
+
+// generateCaseChain compares the tag to every case value in order. Returns the scope entering each case, and leaves the current scope with the control where no value matched.
+func (g *Generator) generateCaseChain(tag Node, values [][]int) ([]*ScopeNode, error) {
+	entries := make([]*ScopeNode, len(values))
+	for i, vs := range values {
+		for _, v := range vs {
+			value, err := peephole(NewConstantNode(types.NewInt(v)))
+			if err != nil {
+				return nil, err
+			}
+			eq, err := peephole(NewBoolNode(tag, EQ, value))
+			if err != nil {
+				return nil, err
+			}
+			ifTrue, ifFalse, err := g.generateBranches(eq)
+			if err != nil {
+				return nil, err
+			}
+			matched := g.Scope.Dup()
+			err = matched.SetControl(ifTrue)
+			if err != nil {
+				return nil, err
+			}
+			err = g.Scope.SetControl(ifFalse)
+			if err != nil {
+				return nil, err
+			}
+			entries[i], err = mergeEntry(entries[i], matched)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// generateJumpTable branches on the tag with a jump table. Returns the scope entering each case, and leaves the current scope with the control of the default.
+func (g *Generator) generateJumpTable(tag Node, values [][]int) ([]*ScopeNode, error) {
+	var all []int
+	for _, vs := range values {
+		all = append(all, vs...)
+	}
+	jt, err := peephole(NewJumpTableNode(g.Scope.Control(), tag, all))
+	if err != nil {
+		return nil, err
+	}
+	// Keep the jump table alive until all projections exist
+	pin(jt)
+	entries := make([]*ScopeNode, len(values))
+	next := 0
+	for i, vs := range values {
+		for _, v := range vs {
+			control, err := g.branchControl(NewProjNode(jt.(MultiNode), next, "Case"+strconv.Itoa(v)))
+			if err != nil {
+				return nil, err
+			}
+			next++
+			matched := g.Scope.Dup()
+			err = matched.SetControl(control)
+			if err != nil {
+				return nil, err
+			}
+			entries[i], err = mergeEntry(entries[i], matched)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	control, err := g.branchControl(NewProjNode(jt.(MultiNode), next, "Default"))
+	if err != nil {
+		return nil, err
+	}
+	unpin(jt)
+	if Unused(jt) {
+		err = kill(jt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, g.Scope.SetControl(control)
+}
+
+// mergeEntry merges the scope of a matched value into the entry of its case, which is nil for the first value.
+func mergeEntry(entry *ScopeNode, matched *ScopeNode) (*ScopeNode, error) {
+	if entry == nil {
+		return matched, nil
+	}
+	_, err := entry.Merge(matched)
+	return entry, err
+}
+
+func (g *Generator) generateBranch(b *ast.BranchStmt) (Node, error) {
+	var err error
+	switch b.Tok {
+	case token.BREAK:
+		if g.breakDepth == 0 {
+			return nil, computeError(b, "break outside of a loop or switch")
+		}
+		g.breakScope, err = g.jumpTo(g.breakScope, g.breakDepth)
+	case token.CONTINUE:
+		if g.continueDepth == 0 {
+			return nil, computeError(b, "continue outside of a loop")
+		}
+		g.continueScope, err = g.jumpTo(g.continueScope, g.continueDepth)
+	default:
+		return nil, astError(b)
+	}
+	return nil, err
+}
+
+// jumpTo merges the current scope into the given jump target with depth nested scopes, and leaves the current scope without control. Returns the merged scope, to may be nil if nothing jumped to it yet.
+func (g *Generator) jumpTo(to *ScopeNode, depth int) (*ScopeNode, error) {
+	if g.Scope.Control() == nil {
+		return to, nil
+	}
+
+	cur := g.Scope.Dup()
+	err := g.Scope.SetControl(nil)
+	if err != nil {
+		return nil, err
+	}
+	// Names defined inside the loop or switch are not visible at the jump target
+	err = cur.PopTo(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == nil {
+		return cur, nil
+	}
+	_, err = to.Merge(cur)
+	return to, err
+}
+
+// generateBranches creates an if node on the current control. Returns the controls of the true and false branches, where a branch that can never be taken has nil control.
+func (g *Generator) generateBranches(pred Node) (Node, Node, error) {
+	ifNode, err := peephole(NewIfNode(g.Scope.Control(), pred))
+	if err != nil {
+		return nil, nil, err
+	}
+	// Keep the if alive until both projections exist
+	pin(ifNode)
+	ifTrue, err := g.branchControl(NewProjNode(ifNode.(MultiNode), 0, "True"))
+	if err != nil {
+		return nil, nil, err
+	}
+	ifFalse, err := g.branchControl(NewProjNode(ifNode.(MultiNode), 1, "False"))
+	if err != nil {
+		return nil, nil, err
+	}
+	unpin(ifNode)
+	// Both projections were optimized away
+	if Unused(ifNode) {
+		err = kill(ifNode)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return ifTrue, ifFalse, nil
+}
+
+// branchControl peepholes a control projection of an if or a call. Returns nil if the control can never be reached.
+func (g *Generator) branchControl(p *ProjNode) (Node, error) {
+	n, err := peephole(p)
+	if err != nil {
+		return nil, err
+	}
+	if Type(n) == types.Top {
+		return nil, kill(n)
+	}
+	return n, nil
+}
+
+func (g *Generator) generateReturn(r *ast.ReturnStmt) (Node, error) {
+	expr, err := g.generateExpr(r.Result)
+	if err != nil {
+		return nil, err
+	}
+	if g.retType != nil {
+		expr, err = coerce(g.retType, expr, r.Result)
+	} else {
+		err = checkPrimitive(expr, r.Result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Unreachable returns are dropped
+	if g.Scope.Control() == nil {
+		return expr, nil
+	}
+
+	if g.retRegion == nil {
+		g.retRegion = NewRegionNode(g.Scope.Control())
+		g.retPhi = NewPhiNode("$ret", Type(expr), g.retRegion, expr)
+	} else {
+		addIn(g.retRegion, g.Scope.Control())
+		addIn(g.retPhi, expr)
+	}
+	return expr, g.Scope.SetControl(nil)
+}
+
+// generateReturnNode creates the ReturnNode from all the returns that were generated. Returns nil if there were none.
+func (g *Generator) generateReturnNode() (*ReturnNode, error) {
+	if g.retRegion == nil {
+		return nil, nil
+	}
+
+	var ret *ReturnNode
+	if NumOfIns(g.retRegion) == 2 {
+		// A single return does not need a merge
+		ret = NewReturnNode(In(g.retRegion, 1), In(g.retPhi, 1))
+		// Killing the phi kills the region as well
+		err := kill(g.retPhi)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		control, err := peephole(g.retRegion)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := peephole(g.retPhi)
+		if err != nil {
+			return nil, err
+		}
+		ret = NewReturnNode(control, expr)
+	}
+	g.retRegion, g.retPhi = nil, nil
+
+	n, err := peephole(ret)
+	if err != nil {
+		return nil, err
+	}
+	return n.(*ReturnNode), nil
+}
+
+func (g *Generator) generateExpr(e ast.Expr) (Node, error) {
+	switch t := e.(type) {
+	case *ast.BinaryExpr:
+		n, err := g.generateBinary(t)
+		return g.wrap(t, n, err)
+	case *ast.ParenExpr:
+		return g.generateExpr(t.X)
+	case *ast.CallExpr:
+		return g.generateCall(t)
+	case *ast.NewExpr:
+		return g.generateNew(t)
+	case *ast.FieldExpr:
+		return g.generateLoad(t)
+	case *ast.LenExpr:
+		return g.generateLength(t)
+	case *ast.IndexExpr:
+		return g.generateIndexLoad(t)
+	case *ast.CondExpr:
+		return g.generateCond(t)
+	case *ast.UnaryExpr:
+		// The literal of the smallest int is only in range with its sign
+		if lit, ok := t.X.(*ast.BasicLit); ok && lit.Kind == token.INT && t.Op == token.SUB {
+			if _, err := strconv.ParseInt(lit.Value, 0, 64); errors.Is(err, strconv.ErrRange) {
+				return generateInt(lit, "-"+lit.Value)
+			}
+		}
+		value, err := g.generateExpr(t.X)
+		if err != nil {
+			return nil, err
+		}
+		switch t.Op {
+		case token.SUB:
+			err = checkNumber(value, t.X)
+			if err != nil {
+				return nil, err
+			}
+			if typeName(Type(value)) == "flt" {
+				return peephole(NewMinusFNode(value))
+			}
+			n, err := peephole(NewMinusNode(value))
+			return g.wrap(t, n, err)
+		case token.NOT:
+			err = checkCondition(value, t.X)
+			if err != nil {
+				return nil, err
+			}
+			return peephole(NewNotNode(value))
+		case token.XOR:
+			// ^x flips all the bits, which is x^-1
+			err = checkType(types.IntBottom, value, t.X)
+			if err != nil {
+				return nil, err
+			}
+			ones, err := peephole(NewConstantNode(types.NewInt(-1)))
+			if err != nil {
+				return nil, err
+			}
+			n, err := peephole(NewXorNode(value, ones))
+			return g.wrap(t, n, err)
+		}
+	case *ast.BasicLit:
+		if t.Kind == token.STRING {
+			return g.generateString(t)
+		}
+		if t.Kind == token.FLOAT {
+			f, err := strconv.ParseFloat(t.Value, 64)
+			if err != nil {
+				return nil, computeError(t, "invalid float "+t.Value)
+			}
+			return peephole(NewConstantNode(types.NewFlt(f)))
+		}
+		return generateInt(t, t.Value)
+	case *ast.Ident:
+		switch t.Name {
+		case "true":
+			return peephole(NewConstantNode(types.True))
+		case "false":
+			return peephole(NewConstantNode(types.False))
+		case "null":
+			return peephole(NewConstantNode(types.Null))
+		}
+		n, ok := g.Scope.Lookup(t.Name)
+		if !ok {
+			return nil, g.unknownIdentifier(t)
+		}
+		if !g.Scope.Initialized(t.Name) {
+			return nil, computeError(e, "variable may be used before assignment: "+t.Name)
+		}
+		return n, nil
+	}
+	return nil, astError(e)
+}
+
+// wrap fits the result of int arithmetic in the width of its sized int type. The nodes compute with 64 bits, so this is where sums, products and negations of sized ints wrap, constants included.
+func (g *Generator) wrap(e ast.Expr, n Node, err error) (Node, error) {
+	if err != nil {
+		return nil, err
+	}
+	s, ok := g.staticType(e).(*types.Int)
+	if !ok || !s.Sized() || typeName(Type(n)) != "int" {
+		return n, nil
+	}
+	return peephole(NewExtendNode(s, n))
+}
+
+// staticType returns the type of an expression from the declared types of the names, fields, elements and functions in it, or nil if it has none, like an int literal. Folded values are constants without a width, so the width of sized ints comes from here. Literals take the type of the other operand, and mixing types gives the smallest type holding both, as in a phi.
+func (g *Generator) staticType(e ast.Expr) types.Type {
+	switch t := e.(type) {
+	case *ast.ParenExpr:
+		return g.staticType(t.X)
+	case *ast.Ident:
+		if typ, ok := g.Scope.DeclaredType(t.Name); ok {
+			return typ
+		}
+	case *ast.BasicLit:
+		if t.Kind == token.STRING && g.bytes != nil {
+			return g.bytes.Ptr()
+		}
+	case *ast.CallExpr:
+		if s, ok := sizedInts[t.Fun.Name]; ok {
+			return s
+		}
+		if fun, ok := g.funcs[t.Fun.Name]; ok {
+			return fun.RetType
+		}
+	case *ast.FieldExpr:
+		return g.fieldType(t.X, t.Field.Name)
+	case *ast.IndexExpr:
+		return g.fieldType(t.X, types.ArrayElems)
+	case *ast.UnaryExpr:
+		return g.staticType(t.X)
+	case *ast.CondExpr:
+		return meetStatic(g.staticType(t.X), g.staticType(t.Y))
+	case *ast.BinaryExpr:
+		switch t.Op {
+		case token.SHL, token.SHR, ast.LShr:
+			// Shifts keep the type of the shifted value
+			return g.staticType(t.X)
+		case token.ADD, token.SUB, token.MUL, token.QUO, token.AND, token.OR, token.XOR:
+			return meetStatic(g.staticType(t.X), g.staticType(t.Y))
+		}
+	}
+	return nil
+}
+
+// checkFits returns an error if an operand without a static type, like a literal, is a constant that does not fit in the sized int type it takes.
+func (g *Generator) checkFits(s *types.Int, n Node, e ast.Expr) error {
+	if g.staticType(e) != nil {
+		return nil
+	}
+	if c, ok := Type(n).(*types.Int); ok && c.Constant() && !s.Contains(c) {
+		return computeError(e, fmt.Sprintf("constant %d overflows %s", c.Value, s.Name()))
+	}
+	return nil
+}
+
+// fieldType returns the declared type of a field of the struct that x refers to, or nil if it is unknown.
+func (g *Generator) fieldType(x ast.Expr, name string) types.Type {
+	if p, ok := g.staticType(x).(*types.MemPtr); ok && p.Struct != nil {
+		if f := p.Struct.Field(name); f != nil {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// meetStatic returns the static type of an expression with operands of the static types, where nil is the type of a literal.
+func meetStatic(l types.Type, r types.Type) types.Type {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	}
+	return l.Meet(r)
+}
+
+// unknownIdentifier reports a name that is not defined, with the closest visible name as a suggestion.
+func (g *Generator) unknownIdentifier(id *ast.Ident) error {
+	if suggestion, ok := g.Scope.Suggest(id.Name); ok {
+		return computeError(id, fmt.Sprintf("unknown identifier, did you mean `%s`?", suggestion))
+	}
+	return computeError(id, "unknown identifier")
+}
+
+// generateString generates the constant u8 array of a string literal. The literal is quoted and escaped as in Go.
+func (g *Generator) generateString(lit *ast.BasicLit) (Node, error) {
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil, computeError(lit, "invalid string "+lit.Value)
+	}
+	return peephole(NewConstantNode(g.bytes.Const(s)))
+}
+
+// generateInt generates the constant of an int literal. The literal may be hex, binary or have underscores, as in Go.
+func generateInt(lit *ast.BasicLit, value string) (Node, error) {
+	num, err := strconv.ParseInt(value, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, computeError(lit, "integer literal out of range: "+value)
+	}
+	if err != nil {
+		return nil, computeError(lit, "invalid integer "+value)
+	}
+	return peephole(NewConstantNode(types.NewInt(int(num))))
+}
+
+func (g *Generator) generateBinary(b *ast.BinaryExpr) (Node, error) {
+	if b.Op == token.LAND || b.Op == token.LOR {
+		return g.generateLogical(b)
+	}
+	lhs, err := g.generateExpr(b.X)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := g.generateExpr(b.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	flt := false
+	switch {
+	case isBitwise(b.Op):
+		err = checkType(types.IntBottom, lhs, b.X)
+		if err == nil {
+			err = checkType(types.IntBottom, rhs, b.Y)
+		}
+	case (!isNumber(lhs) || !isNumber(rhs)) && (b.Op == token.EQL || b.Op == token.NEQ):
+		// References and bools can only be compared for equality, and only to the same type
+		err = g.checkComparable(lhs, rhs, b)
+	case !isNumber(lhs):
+		err = checkNumber(lhs, b.X)
+	case !isNumber(rhs):
+		err = checkNumber(rhs, b.Y)
+	case typeName(Type(lhs)) == "flt" || typeName(Type(rhs)) == "flt":
+		// Ints are widened when mixed with floats
+		flt = true
+		lhs, err = coerce(types.FltBottom, lhs, b.X)
+		if err == nil {
+			rhs, err = coerce(types.FltBottom, rhs, b.Y)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Literals take the sized int type of the other operand, so they must fit in it. The amount of a shift is not converted.
+	if s, ok := g.staticType(b).(*types.Int); ok && s.Sized() && !flt && b.Op != token.SHL && b.Op != token.SHR && b.Op != ast.LShr {
+		err = g.checkFits(s, lhs, b.X)
+		if err == nil {
+			err = g.checkFits(s, rhs, b.Y)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch b.Op {
+	case token.ADD:
+		if flt {
+			return peephole(NewAddFNode(lhs, rhs))
+		}
+		return peephole(NewAddNode(lhs, rhs))
+	case token.SUB:
+		if flt {
+			return peephole(NewSubFNode(lhs, rhs))
+		}
+		return peephole(NewSubNode(lhs, rhs))
+	case token.MUL:
+		if flt {
+			return peephole(NewMulFNode(lhs, rhs))
+		}
+		return peephole(NewMulNode(lhs, rhs))
+	case token.QUO:
+		if flt {
+			return peephole(NewDivFNode(lhs, rhs))
+		}
+		div := NewDivNode(lhs, rhs)
+		// A constant divisor of zero is reported at the divisor
+		div.expr = b.Y
+		return peephole(div)
+	case token.AND:
+		return peephole(NewAndNode(lhs, rhs))
+	case token.OR:
+		return peephole(NewOrNode(lhs, rhs))
+	case token.XOR:
+		return peephole(NewXorNode(lhs, rhs))
+	case token.SHL:
+		return peephole(NewShlNode(lhs, rhs))
+	case token.SHR:
+		return peephole(NewAShrNode(lhs, rhs))
+	case ast.LShr:
+		return peephole(NewLShrNode(lhs, rhs))
+	}
+	return g.generateCompare(b, lhs, rhs, flt)
+}
+
+// checkComparable returns an error if the nodes cannot be compared for equality. References to the same struct can be compared whether they are nullable or not, and any reference can be compared to null. The structs come from the declared types where there are some, because a reference that is always null has none.
+func (g *Generator) checkComparable(lhs Node, rhs Node, b *ast.BinaryExpr) error {
+	l, lOk := g.refType(b.X, lhs)
+	r, rOk := g.refType(b.Y, rhs)
+	if !lOk || !rOk {
+		return checkType(Type(lhs), rhs, b.Y)
+	}
+	if l.Struct != nil && r.Struct != nil && l.Struct != r.Struct {
+		return computeError(b.Y, fmt.Sprintf("expected %s, got %s", typeName(l), typeName(r)))
+	}
+	return nil
+}
+
+// refType returns the reference type of an operand, preferring its static type to the type of its node.
+func (g *Generator) refType(e ast.Expr, n Node) (*types.MemPtr, bool) {
+	if p, ok := g.staticType(e).(*types.MemPtr); ok {
+		return p, true
+	}
+	p, ok := Type(n).(*types.MemPtr)
+	return p, ok
+}
+
+// isBitwise returns true for the bitwise and shift operators, which only apply to ints.
+func isBitwise(op token.Token) bool {
+	switch op {
+	case token.AND, token.OR, token.XOR, token.SHL, token.SHR, ast.LShr:
+		return true
+	}
+	return false
+}
+
+// generateLogical generates a short-circuit && or ||. The rhs is only evaluated on the branch where the lhs does not decide the result, and the results of both branches are merged with a phi. When the lhs is a constant no control flow is generated, and the rhs is dropped if it is never evaluated.
+func (g *Generator) generateLogical(b *ast.BinaryExpr) (Node, error) {
+	lhs, err := g.generateExpr(b.X)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCondition(lhs, b.X)
+	if err != nil {
+		return nil, err
+	}
+
+	// The result when the rhs is skipped: false for &&, true for ||
+	skipped := b.Op == token.LOR
+	if pred, ok := constantCondition(lhs); ok {
+		if pred == skipped {
+			return peephole(NewConstantNode(types.NewBool(skipped)))
+		}
+		return g.generateLogicalRhs(b.Y)
+	}
+
+	// The result is a hidden name, so the scopes of the branches merge it like any variable
+	g.Scope.Push()
+	result, err := peephole(NewConstantNode(types.NewBool(skipped)))
+	if err != nil {
+		return nil, err
+	}
+	err = g.Scope.Define(logicalResult, types.BoolBottom, result)
+	if err != nil {
+		return nil, err
+	}
+
+	ifTrue, ifFalse, err := g.generateBranches(lhs)
+	if err != nil {
+		return nil, err
+	}
+	evalControl, skipControl := ifTrue, ifFalse
+	if b.Op == token.LOR {
+		evalControl, skipControl = ifFalse, ifTrue
+	}
+
+	skipScope := g.Scope.Dup()
+	err = skipScope.SetControl(skipControl)
+	if err != nil {
+		return nil, err
+	}
+	err = g.Scope.SetControl(evalControl)
+	if err != nil {
+		return nil, err
+	}
+	// The rhs is generated where the lhs is true for &&, and false for ||, so p && p.x sees p as not null
+	if b.Op == token.LAND {
+		err = g.refineBranches(b.X, g.Scope, ifTrue, skipScope, ifFalse)
+	} else {
+		err = g.refineBranches(b.X, skipScope, ifTrue, g.Scope, ifFalse)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := g.generateLogicalRhs(b.Y)
+	if err != nil {
+		return nil, err
+	}
+	_, err = g.Scope.Update(logicalResult, rhs)
+	if err != nil {
+		return nil, err
+	}
+	_, err = g.Scope.Merge(skipScope)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ = g.Scope.Lookup(logicalResult)
+	// Keep the result alive while its name is popped
+	pin(result)
+	defer unpin(result)
+	return result, g.Scope.Pop()
+}
+
+// logicalResult is the name of the result of && and || in the scope. It cannot clash with identifiers.
+const logicalResult = "$logical"
+
+// generateCond generates c ? x : y. Only the chosen operand is evaluated, so each is generated in its own branch. Ints are widened to floats if the other operand is a float.
+func (g *Generator) generateCond(c *ast.CondExpr) (Node, error) {
+	pred, err := g.generateExpr(c.Cond)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCondition(pred, c.Cond)
+	if err != nil {
+		return nil, err
+	}
+	if pred, ok := constantCondition(pred); ok {
+		if pred {
+			return g.generateExpr(c.X)
+		}
+		return g.generateExpr(c.Y)
+	}
+
+	ifTrue, ifFalse, err := g.generateBranches(pred)
+	if err != nil {
+		return nil, err
+	}
+	falseScope := g.Scope.Dup()
+	err = g.Scope.SetControl(ifTrue)
+	if err != nil {
+		return nil, err
+	}
+	err = g.refineBranches(c.Cond, g.Scope, ifTrue, falseScope, ifFalse)
+	if err != nil {
+		return nil, err
+	}
+	x, err := g.generateExpr(c.X)
+	if err != nil {
+		return nil, err
+	}
+	trueScope := g.Scope
+
+	g.Scope = falseScope
+	err = g.Scope.SetControl(ifFalse)
+	if err != nil {
+		return nil, err
+	}
+	// Keep x alive while y is generated
+	pin(x)
+	y, err := g.generateExpr(c.Y)
+	unpin(x)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := inferType(Type(x))
+	if typeName(Type(x)) == "int" && typeName(Type(y)) == "flt" {
+		typ = types.FltBottom
+	}
+	x, err = coerce(typ, x, c.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err = coerce(typ, y, c.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	// The result is a hidden name, so the scopes of the branches merge it like any variable
+	trueScope.Push()
+	err = trueScope.Define(condResult, typ, x)
+	if err != nil {
+		return nil, err
+	}
+	falseScope.Push()
+	err = falseScope.Define(condResult, typ, y)
+	if err != nil {
+		return nil, err
+	}
+	g.Scope = trueScope
+	_, err = g.Scope.Merge(falseScope)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := g.Scope.Lookup(condResult)
+	// Keep the result alive while its name is popped
+	pin(result)
+	defer unpin(result)
+	return result, g.Scope.Pop()
+}
+
+// condResult is the name of the result of c ? x : y in the scope.
+const condResult = "$cond"
+
+// generateLogicalRhs generates the rhs of && or || as a bool. Ints are compared to zero.
+func (g *Generator) generateLogicalRhs(e ast.Expr) (Node, error) {
+	rhs, err := g.generateExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCondition(rhs, e)
+	if err != nil {
+		return nil, err
+	}
+	if typeName(Type(rhs)) == "bool" {
+		return rhs, nil
+	}
+	zero, err := peephole(NewConstantNode(types.NewInt(0)))
+	if err != nil {
+		return nil, err
+	}
+	eq, err := peephole(NewBoolNode(rhs, EQ, zero))
+	if err != nil {
+		return nil, err
+	}
+	return peephole(NewNotNode(eq))
+}
+
+// generateCompare generates a comparison of lhs and rhs. Greater than comparisons swap the operands, and != negates ==, which is also correct for NaN.
+func (g *Generator) generateCompare(b *ast.BinaryExpr, lhs Node, rhs Node, flt bool) (Node, error) {
+	newBool := func(lhs Node, op BoolType, rhs Node) Node {
+		if flt {
+			return NewBoolFNode(lhs, op, rhs)
+		}
+		return NewBoolNode(lhs, op, rhs)
+	}
+
+	switch b.Op {
+	case token.EQL:
+		return peephole(newBool(lhs, EQ, rhs))
+	case token.GEQ:
+		lhs, rhs = rhs, lhs
+		fallthrough
+	case token.LEQ:
+		return peephole(newBool(lhs, LE, rhs))
+	case token.GTR:
+		lhs, rhs = rhs, lhs
+		fallthrough
+	case token.LSS:
+		return peephole(newBool(lhs, LT, rhs))
+	case token.NEQ:
+		eq, err := peephole(newBool(lhs, EQ, rhs))
+		if err != nil {
+			return nil, err
+		}
+		return peephole(NewNotNode(eq))
+	}
+	return nil, astError(b)
 }
@@ -3,8 +3,7 @@ package ir
 import (
 	"testing"
 
-	goast "go/ast"
-
+	sast "github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/utils/ast"
 	"github.com/stretchr/testify/suite"
@@ -26,7 +25,7 @@ func (suite *GeneratorTestSuite) TestPrint() {
 func (suite *GeneratorTestSuite) TestOperations() {
 	subTests := []struct {
 		name     string
-		input    *goast.ReturnStmt
+		input    *sast.ReturnStmt
 		expected string
 	}{
 		{name: "add", input: ast.Ret(ast.Bin(1, "+", 2)), expected: "return 3;"},
@@ -50,7 +49,7 @@ func (suite *GeneratorTestSuite) TestOperations() {
 func (suite *GeneratorTestSuite) TestVars() {
 	subTests := []struct {
 		name     string
-		input    *goast.BlockStmt
+		input    *sast.BlockStmt
 		expected string
 	}{
 		{name: "ret", input: ast.Block(ast.Decl("a", 1), ast.Ret("a")), expected: "return 1;"},
@@ -69,10 +68,37 @@ func (suite *GeneratorTestSuite) TestVars() {
 	}
 }
 
+func (suite *GeneratorTestSuite) TestAssignOps() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "compound", input: ast.Block(ast.Decl("a", "arg"), ast.AssignOp("a", "*=", 2), ast.Ret("a")), expected: "return (arg*2);"},
+		{name: "increment", input: ast.Block(ast.Decl("a", 1), ast.IncDec("a", "++"), ast.Ret("a")), expected: "return 2;"},
+		{name: "decrement", input: ast.Block(ast.Decl("a", 1), ast.IncDec("a", "--"), ast.Ret("a")), expected: "return 0;"},
+		{name: "inferred", input: ast.Block(ast.Var("f", 1.5), ast.AssignOp("f", "+=", 1), ast.Ret("f")), expected: "return 2.5;"},
+		{name: "val", input: ast.Block(ast.Val("a", "arg"), ast.Ret(ast.Bin("a", "-", 1))), expected: "return (arg-1);"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestAssignVal() {
+	_, err := NewGenerator(types.Bottom).Generate(ast.Block(ast.Val("a", 1), ast.IncDec("a", "++"), ast.Ret("a")))
+	suite.ErrorContains(err, "cannot assign to val a")
+}
+
 func (suite *GeneratorTestSuite) TestUnknownIdent() {
 	subTests := []struct {
 		name  string
-		input goast.Stmt
+		input sast.Stmt
 	}{
 		{name: "ret", input: ast.Ret("a")},
 		{name: "assign", input: ast.Assign("a", 1)},
@@ -91,7 +117,7 @@ func (suite *GeneratorTestSuite) TestUnknownIdent() {
 func (suite *GeneratorTestSuite) TestArg() {
 	subTests := []struct {
 		name     string
-		input    *goast.BlockStmt
+		input    *sast.BlockStmt
 		expected string
 	}{
 		{name: "add1", input: ast.Block(ast.Ret(ast.Bin(ast.Bin(1, "+", "arg"), "+", 2))), expected: "return (arg+3);"},
@@ -123,13 +149,13 @@ func (suite *GeneratorTestSuite) TestConstArg() {
 func (suite *GeneratorTestSuite) TestBool() {
 	subTests := []struct {
 		name     string
-		input    *goast.BlockStmt
+		input    *sast.BlockStmt
 		expected string
 	}{
-		{name: "eq true", input: ast.Block(ast.Ret(ast.Bin(3, "==", 3))), expected: "return 1;"},
-		{name: "eq false", input: ast.Block(ast.Ret(ast.Bin(3, "==", 4))), expected: "return 0;"},
-		{name: "neq true", input: ast.Block(ast.Ret(ast.Bin(3, "!=", 4))), expected: "return 1;"},
-		{name: "neq false", input: ast.Block(ast.Ret(ast.Bin(3, "!=", 3))), expected: "return 0;"},
+		{name: "eq true", input: ast.Block(ast.Ret(ast.Bin(3, "==", 3))), expected: "return true;"},
+		{name: "eq false", input: ast.Block(ast.Ret(ast.Bin(3, "==", 4))), expected: "return false;"},
+		{name: "neq true", input: ast.Block(ast.Ret(ast.Bin(3, "!=", 4))), expected: "return true;"},
+		{name: "neq false", input: ast.Block(ast.Ret(ast.Bin(3, "!=", 3))), expected: "return false;"},
 	}
 
 	for _, test := range subTests {
@@ -141,6 +167,879 @@ func (suite *GeneratorTestSuite) TestBool() {
 	}
 }
 
+func (suite *GeneratorTestSuite) TestIf() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "if else", input: ast.Block(ast.Decl("a", 1), ast.If("arg", ast.Assign("a", 2), ast.Assign("a", 3)), ast.Ret("a")), expected: "return Phi(Region11,2,3);"},
+		{name: "no else", input: ast.Block(ast.Decl("a", 1), ast.If("arg", ast.Assign("a", 2), nil), ast.Ret("a")), expected: "return Phi(Region10,2,1);"},
+		{name: "unchanged", input: ast.Block(ast.Decl("a", 1), ast.If("arg", ast.Assign("a", 1), nil), ast.Ret("a")), expected: "return 1;"},
+		{name: "constant pred", input: ast.Block(ast.Decl("a", 1), ast.If(0, ast.Assign("a", 2), ast.Assign("a", 3)), ast.Ret("a")), expected: "return 3;"},
+		{name: "returns", input: ast.Block(ast.If("arg", ast.Ret(1), nil), ast.Ret(2)), expected: "return Phi(Region9,1,2);"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestDeadBranch() {
+	input := ast.Block(ast.Decl("a", 1), ast.If(1, ast.Assign("a", 2), ast.Assign("a", 3)), ast.Ret("a"))
+	retNode, err := NewGenerator(types.Bottom).Generate(input)
+	suite.NoError(err)
+	// The if is removed, so control comes straight from start
+	suite.Equal(StartNode, In(retNode.Control(), 0))
+	for _, n := range allNodes() {
+		suite.NotEqual("If", n.label())
+	}
+}
+
+func (suite *GeneratorTestSuite) TestWhile() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{
+			name:     "loop carried",
+			input:    ast.Block(ast.While(ast.Bin("arg", "<", 10), ast.Assign("arg", ast.Bin("arg", "+", 1))), ast.Ret("arg")),
+			expected: "return Phi(Loop4,arg,(Phi_arg+1));",
+		},
+		{
+			name:     "unchanged in loop",
+			input:    ast.Block(ast.Decl("a", 1), ast.While(ast.Bin("arg", "<", 10), ast.Assign("arg", ast.Bin("arg", "+", 1))), ast.Ret("a")),
+			expected: "return 1;",
+		},
+		{
+			name: "two carried",
+			input: ast.Block(
+				ast.Decl("a", 1),
+				ast.Decl("b", 2),
+				ast.While(ast.Bin("a", "<", 10),
+					ast.Assign("a", ast.Bin("a", "+", 1)),
+					ast.Assign("b", ast.Bin("b", "+", 2)),
+				),
+				ast.Ret("b"),
+			),
+			expected: "return Phi(Loop6,2,(Phi_b+2));",
+		},
+		{
+			name: "nested",
+			input: ast.Block(
+				ast.Decl("sum", 0),
+				ast.Decl("i", 0),
+				ast.While(ast.Bin("i", "<", "arg"),
+					ast.Assign("i", ast.Bin("i", "+", 1)),
+					ast.Decl("j", 0),
+					ast.While(ast.Bin("j", "<", "i"),
+						ast.Assign("sum", ast.Bin("sum", "+", "j")),
+						ast.Assign("j", ast.Bin("j", "+", 1)),
+					),
+				),
+				ast.Ret("sum"),
+			),
+			expected: "return Phi(Loop6,0,Phi(Loop19,Phi_sum,(Phi(Loop19,0,(Phi_j+1))+Phi_sum)));",
+		},
+		{
+			name:     "never taken",
+			input:    ast.Block(ast.Decl("a", 1), ast.While(0, ast.Assign("a", 2)), ast.Ret("a")),
+			expected: "return 1;",
+		},
+		{
+			name:     "return in loop",
+			input:    ast.Block(ast.While(ast.Bin("arg", "<", 10), ast.If(ast.Bin("arg", "==", 5), ast.Ret(1), nil), ast.Assign("arg", ast.Bin("arg", "+", 1))), ast.Ret(0)),
+			expected: "return Phi(Region20,1,0);",
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestWhileNeverTakenRemovesLoop() {
+	input := ast.Block(ast.Decl("a", 1), ast.While(0, ast.Assign("a", 2)), ast.Ret("a"))
+	retNode, err := NewGenerator(types.Bottom).Generate(input)
+	suite.NoError(err)
+	suite.Equal(StartNode, In(retNode.Control(), 0))
+}
+
+func (suite *GeneratorTestSuite) TestBreakContinue() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{
+			name: "break",
+			input: ast.Block(
+				ast.While(1, ast.Assign("arg", ast.Bin("arg", "+", 1)), ast.If(ast.Bin("arg", "==", 5), ast.Break(), nil)),
+				ast.Ret("arg"),
+			),
+			expected: "return (Phi(Loop4,arg,(Phi_arg+1))+1);",
+		},
+		{
+			name: "continue",
+			input: ast.Block(
+				ast.Decl("a", 0),
+				ast.While(ast.Bin("arg", "<", 10),
+					ast.Assign("arg", ast.Bin("arg", "+", 1)),
+					ast.If(ast.Bin("arg", "==", 5), ast.Continue(), nil),
+					ast.Assign("a", ast.Bin("a", "+", 1)),
+				),
+				ast.Ret("a"),
+			),
+			expected: "return Phi(Loop5,0,Phi(Region27,Phi_a,(Phi_a+1)));",
+		},
+		{
+			name:     "dead code after break",
+			input:    ast.Block(ast.Decl("a", 0), ast.While("arg", ast.Break(), ast.Assign("a", 1)), ast.Ret("a")),
+			expected: "return 0;",
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestBreakOutsideLoop() {
+	_, err := NewGenerator(types.Bottom).Generate(ast.Block(ast.Break(), ast.Ret(1)))
+	suite.ErrorContains(err, "break outside of a loop")
+	_, err = NewGenerator(types.Bottom).Generate(ast.Block(ast.Continue(), ast.Ret(1)))
+	suite.ErrorContains(err, "continue outside of a loop")
+}
+
+func (suite *GeneratorTestSuite) TestSwitch() {
+	input := func() *sast.BlockStmt {
+		return ast.Block(
+			ast.Decl("r", 0),
+			ast.Switch("arg",
+				ast.Case([]any{1}, ast.Assign("r", 10)),
+				ast.Case([]any{2}, ast.Assign("r", 20)),
+				ast.Case([]any{3, 4}, ast.Assign("r", 30)),
+			),
+			ast.Ret("r"),
+		)
+	}
+	jumpTables := func() []Node {
+		var found []Node
+		for _, n := range allNodes() {
+			if _, ok := n.(*JumpTableNode); ok {
+				found = append(found, n)
+			}
+		}
+		return found
+	}
+
+	retNode, err := NewGenerator(types.Bottom).Generate(input())
+	suite.Require().NoError(err)
+	suite.Equal("return Phi(Region45,Phi(Region42,Phi(Region38,10,20),30),0);", ToString(retNode))
+	suite.Empty(jumpTables())
+
+	JumpTables = true
+	defer func() { JumpTables = false }()
+	retNode, err = NewGenerator(types.Bottom).Generate(input())
+	suite.Require().NoError(err)
+	found := jumpTables()
+	suite.Require().Len(found, 1)
+	suite.Equal("switch( arg )", ToString(found[0]))
+}
+
+func (suite *GeneratorTestSuite) TestCondExpr() {
+	input := ast.Block(ast.Ret(ast.Cond(ast.Bin("arg", "<", 0), ast.Un("-", "arg"), "arg")))
+	retNode, err := NewGenerator(types.Bottom).Generate(input)
+	suite.Require().NoError(err)
+	suite.Equal("return Phi(Region11,(-arg),arg);", ToString(retNode))
+}
+
+func (suite *GeneratorTestSuite) TestSizedInts() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+		typ      types.Type
+	}{
+		{
+			name:     "wrap",
+			input:    ast.Block(ast.TypedDecl("i8", "a", 128), ast.Ret("a")),
+			expected: "return -128;",
+			typ:      types.NewInt(-128),
+		},
+		{
+			name:     "extend",
+			input:    ast.Block(ast.TypedDecl("u16", "a", "arg"), ast.Ret("a")),
+			expected: "return u16(arg);",
+			typ:      types.U16,
+		},
+		{
+			name: "phi keeps the width",
+			input: ast.Block(
+				ast.TypedDecl("i8", "a", "arg"),
+				ast.TypedDecl("i8", "b", 0),
+				ast.If("arg", ast.Assign("b", "a"), ast.Assign("b", -1)),
+				ast.TypedDecl("i8", "c", "b"),
+				ast.Ret("c"),
+			),
+			expected: "return Phi(Region16,i8(arg),-1);",
+			typ:      types.I8,
+		},
+		{
+			name: "phi widens",
+			input: ast.Block(
+				ast.TypedDecl("u8", "a", "arg"),
+				ast.TypedDecl("i8", "b", "arg"),
+				ast.TypedDecl("i16", "c", 0),
+				ast.If("arg", ast.Assign("c", "a"), ast.Assign("c", "b")),
+				ast.Ret("c"),
+			),
+			expected: "return Phi(Region15,u8(arg),i8(arg));",
+			typ:      types.I16,
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.IntBottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+			suite.Equal(test.typ, Type(retNode.Expr()))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestNullable() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+		casts    int
+	}{
+		{
+			name:     "null",
+			input:    ast.Block(ast.Struct("S", "x"), ast.NullableDecl("S", "p", "null"), ast.Ret(ast.Bin("p", "==", "null"))),
+			expected: "return true;",
+		},
+		{
+			name: "cast after check",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", "null"),
+				ast.If("arg", ast.Assign("p", ast.New("S")), nil),
+				ast.If("p", ast.Ret(ast.Field("p", "x")), nil),
+				ast.Ret(1),
+			),
+			expected: "return Phi(Region22,(*S)Phi(Region13,new S,null).x,1);",
+			casts:    1,
+		},
+		{
+			name: "cast removed",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", ast.New("S")),
+				ast.If("p", ast.Ret(ast.Field("p", "x")), nil),
+				ast.Ret(1),
+			),
+			expected: "return 0;",
+		},
+		{
+			name: "merge drops the cast",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", "null"),
+				ast.If("arg", ast.Assign("p", ast.New("S")), nil),
+				ast.If(ast.Bin("p", "!=", "null"), ast.Store("p", "x", 1), nil),
+				ast.Ret(ast.Bin("p", "==", "null")),
+			),
+			expected: "return (Phi(Region13,new S,null)==null);",
+		},
+		{
+			name: "cast in the rhs of and",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", "null"),
+				ast.If("arg", ast.Assign("p", ast.New("S")), nil),
+				ast.Ret(ast.Bin(ast.Bin("p", "!=", "null"), "&&", ast.Bin(ast.Field("p", "x"), "==", 1))),
+			),
+			expected: "return Phi(Region28,((*S)Phi(Region13,new S,null).x==1),false);",
+			casts:    1,
+		},
+		{
+			name: "cast in the rhs of or",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", "null"),
+				ast.If("arg", ast.Assign("p", ast.New("S")), nil),
+				ast.Ret(ast.Bin(ast.Bin("p", "==", "null"), "||", ast.Bin(ast.Field("p", "x"), "==", 1))),
+			),
+			expected: "return Phi(Region27,((*S)Phi(Region13,new S,null).x==1),true);",
+			casts:    1,
+		},
+		{
+			name: "cast in the true operand",
+			input: ast.Block(
+				ast.Struct("S", "x"),
+				ast.NullableDecl("S", "p", "null"),
+				ast.If("arg", ast.Assign("p", ast.New("S")), nil),
+				ast.Ret(ast.Cond("p", ast.Field("p", "x"), 0)),
+			),
+			expected: "return Phi(Region23,(*S)Phi(Region13,new S,null).x,0);",
+			casts:    1,
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.IntBottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+			casts := 0
+			for _, n := range allNodes() {
+				if _, ok := n.(*CastNode); ok {
+					casts++
+				}
+			}
+			suite.Equal(test.casts, casts)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestStrings() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{
+			name:     "length",
+			input:    ast.Block(ast.Ret(ast.Len(ast.Str(`"a\nb"`)))),
+			expected: "return 3;",
+		},
+		{
+			name:     "element",
+			input:    ast.Block(ast.Ret(ast.Index(ast.Str(`"AB"`), 1))),
+			expected: "return 66;",
+		},
+		{
+			name:     "interned",
+			input:    ast.Block(ast.Var("s", ast.Str(`"ab"`)), ast.Ret(ast.Bin("s", "==", ast.Str(`"a\x62"`)))),
+			expected: "return true;",
+		},
+		{
+			name: "unknown element",
+			input: ast.Block(
+				&sast.VarDecl{Name: ast.ID("s"), Type: &sast.ArrayType{Elt: ast.ID("u8")}, Value: ast.Str(`"ab"`)},
+				ast.Ret(ast.Index("s", "arg")),
+			),
+			expected: `return "ab"[arg];`,
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.IntBottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestFunctions() {
+	subTests := []struct {
+		name      string
+		input     *sast.BlockStmt
+		expected  string
+		functions []string
+	}{
+		{
+			name:      "call",
+			input:     ast.Block(ast.Func("sq", []string{"x"}, ast.Ret(ast.Bin("x", "*", "x"))), ast.Ret(ast.Call("sq", "arg"))),
+			expected:  "return sq(arg);",
+			functions: []string{"return (x*x);"},
+		},
+		{
+			name:      "constant return",
+			input:     ast.Block(ast.Func("one", nil, ast.Ret(1)), ast.Ret(ast.Bin(ast.Call("one"), "+", 1))),
+			expected:  "return 2;",
+			functions: []string{"return 1;"},
+		},
+		{
+			name: "declared after use",
+			input: ast.Block(
+				ast.Func("f", []string{"a"}, ast.Ret(ast.Call("g", "a", 1))),
+				ast.Func("g", []string{"a", "b"}, ast.Ret(ast.Bin("a", "-", "b"))),
+				ast.Ret(ast.Call("f", "arg")),
+			),
+			expected:  "return f(arg);",
+			functions: []string{"return g(a,1);", "return (a-b);"},
+		},
+		{
+			name:      "recursive",
+			input:     ast.Block(ast.Func("f", []string{"n"}, ast.If("n", ast.Ret(ast.Call("f", ast.Bin("n", "-", 1))), nil), ast.Ret(0)), ast.Ret(ast.Call("f", 3))),
+			expected:  "return f(3);",
+			functions: []string{"return Phi(Region18,f((n-1)),0);"},
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			g := NewGenerator(types.Bottom)
+			retNode, err := g.Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+			suite.Require().Len(g.Functions, len(test.functions))
+			for i, fun := range g.Functions {
+				suite.Equal(test.functions[i], ToString(fun.Ret))
+			}
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestRecursiveCallLinked() {
+	input := ast.Block(ast.Func("f", []string{"n"}, ast.Ret(ast.Call("f", "n"))), ast.Ret(ast.Call("f", 1)))
+	g := NewGenerator(types.Bottom)
+	_, err := g.Generate(input)
+	suite.Require().NoError(err)
+	for _, c := range g.callEnds {
+		suite.Equal(g.Functions[0].Ret, In(c, 1))
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidFunctions() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "unknown function", input: ast.Block(ast.Ret(ast.Call("f"))), error: "unknown function"},
+		{name: "arguments", input: ast.Block(ast.Func("f", []string{"a"}, ast.Ret("a")), ast.Ret(ast.Call("f"))), error: "f expects 1 arguments, got 0"},
+		{name: "redefined", input: ast.Block(ast.Func("f", nil, ast.Ret(1)), ast.Func("f", nil, ast.Ret(2)), ast.Ret(1)), error: "function already defined: f"},
+		{name: "missing return", input: ast.Block(ast.Func("f", nil, ast.Decl("a", 1)), ast.Ret(1)), error: "missing return in function f"},
+		{name: "nested", input: ast.Block(ast.Block(ast.Func("f", nil, ast.Ret(1))), ast.Ret(1)), error: "functions can only be declared at the top level"},
+		{name: "no outer names", input: ast.Block(ast.Decl("a", 1), ast.Func("f", nil, ast.Ret("a")), ast.Ret(1)), error: "unknown identifier"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestStructs() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{
+			name:     "zero initialized",
+			input:    ast.Block(ast.Struct("P", "x", "y"), ast.TypedDecl("P", "p", ast.New("P")), ast.Ret(ast.Field("p", "y"))),
+			expected: "return 0;",
+		},
+		{
+			name:     "store to load",
+			input:    ast.Block(ast.Struct("P", "x"), ast.TypedDecl("P", "p", ast.New("P")), ast.Store("p", "x", "arg"), ast.Ret(ast.Field("p", "x"))),
+			expected: "return arg;",
+		},
+		{
+			name: "distinct allocations",
+			input: ast.Block(
+				ast.Struct("P", "x"),
+				ast.TypedDecl("P", "p", ast.New("P")),
+				ast.TypedDecl("P", "q", ast.New("P")),
+				ast.Store("p", "x", 1),
+				ast.Store("q", "x", 2),
+				ast.Ret(ast.Field("p", "x")),
+			),
+			expected: "return 1;",
+		},
+		{
+			name: "merged stores",
+			input: ast.Block(
+				ast.Struct("P", "x"),
+				ast.TypedDecl("P", "p", ast.New("P")),
+				ast.If("arg", ast.Store("p", "x", 1), ast.Block(ast.Store("p", "x", 2))),
+				ast.Ret(ast.Field("p", "x")),
+			),
+			expected: "return Phi(Region16,1,2);",
+		},
+		{
+			name: "function memory",
+			input: ast.Block(
+				ast.Struct("P", "x"),
+				ast.Func("f", []string{"a"}, ast.TypedDecl("P", "p", ast.New("P")), ast.Store("p", "x", "a"), ast.Ret(ast.Field("p", "x"))),
+				ast.Ret(ast.Call("f", "arg")),
+			),
+			expected: "return f(arg);",
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestStoreInLoop() {
+	input := ast.Block(
+		ast.Struct("P", "x"),
+		ast.TypedDecl("P", "p", ast.New("P")),
+		ast.While(ast.Bin("arg", "<", 10), ast.Store("p", "x", ast.Bin(ast.Field("p", "x"), "+", 1)), ast.Assign("arg", ast.Bin("arg", "+", 1))),
+		ast.Ret(ast.Field("p", "x")),
+	)
+	retNode, err := NewGenerator(types.Bottom).Generate(input)
+	suite.Require().NoError(err)
+
+	load, ok := retNode.Expr().(*LoadNode)
+	suite.Require().True(ok)
+	suite.IsType(&NewNode{}, load.Ptr())
+	suite.IsType(&PhiNode{}, load.Mem())
+	suite.Equal(types.IntBottom, Type(load))
+}
+
+func (suite *GeneratorTestSuite) TestInvalidStructs() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "unknown type", input: ast.Block(ast.TypedDecl("P", "p", 1), ast.Ret(1)), error: "unknown type P"},
+		{name: "unknown struct", input: ast.Block(ast.Ret(ast.New("P"))), error: "unknown struct P"},
+		{name: "unknown field", input: ast.Block(ast.Struct("P", "x"), ast.TypedDecl("P", "p", ast.New("P")), ast.Ret(ast.Field("p", "y"))), error: "unknown field y in P"},
+		{name: "not a struct", input: ast.Block(ast.Ret(ast.Field("arg", "x"))), error: "expected a struct, got int"},
+		{name: "int to struct", input: ast.Block(ast.Struct("P", "x"), ast.TypedDecl("P", "p", 1), ast.Ret(1)), error: "expected P, got int"},
+		{name: "struct to int", input: ast.Block(ast.Struct("P", "x"), ast.Decl("a", ast.New("P")), ast.Ret(1)), error: "expected int, got P"},
+		{name: "different structs", input: ast.Block(ast.Struct("P", "x"), ast.Struct("Q", "x"), ast.TypedDecl("P", "p", ast.New("Q")), ast.Ret(1)), error: "expected P, got Q"},
+		{name: "arithmetic", input: ast.Block(ast.Struct("P", "x"), ast.Ret(ast.Bin(ast.New("P"), "+", 1))), error: "expected int or flt, got P"},
+		{name: "redefined", input: ast.Block(ast.Struct("P", "x"), ast.Struct("P", "y"), ast.Ret(1)), error: "struct already defined: P"},
+		{name: "duplicate field", input: ast.Block(ast.Struct("P", "x", "x"), ast.Ret(1)), error: "field already defined: x"},
+		{name: "nested", input: ast.Block(ast.Block(ast.Struct("P", "x")), ast.Ret(1)), error: "structs can only be declared at the top level"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestArrays() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{
+			name:     "length",
+			input:    ast.Block(ast.ArrayDecl("a", ast.NewArray("arg")), ast.Ret(ast.Len("a"))),
+			expected: "return arg;",
+		},
+		{
+			name:     "zero initialized",
+			input:    ast.Block(ast.ArrayDecl("a", ast.NewArray(3)), ast.Ret(ast.Index("a", 2))),
+			expected: "return 0;",
+		},
+		{
+			name: "store to load",
+			input: ast.Block(
+				ast.ArrayDecl("a", ast.NewArray(3)),
+				ast.StoreIndex("a", 1, "arg"),
+				ast.StoreIndex("a", 2, 7),
+				ast.Ret(ast.Bin(ast.Index("a", 1), "+", ast.Index("a", 2))),
+			),
+			expected: "return (arg+7);",
+		},
+		{
+			name: "distinct allocations",
+			input: ast.Block(
+				ast.ArrayDecl("a", ast.NewArray(3)),
+				ast.ArrayDecl("b", ast.NewArray(3)),
+				ast.StoreIndex("a", 0, 1),
+				ast.StoreIndex("b", 0, 2),
+				ast.Ret(ast.Index("a", 0)),
+			),
+			expected: "return 1;",
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestBoundsCheck() {
+	subTests := []struct {
+		name    string
+		length  any
+		index   any
+		checked bool
+	}{
+		{name: "in bounds", length: 3, index: 2, checked: false},
+		{name: "range in bounds", length: 256, index: ast.Bin("arg", "&", 255), checked: false},
+		{name: "range out of bounds", length: 255, index: ast.Bin("arg", "&", 255), checked: true},
+		{name: "unknown index", length: 3, index: "arg", checked: true},
+		{name: "unknown length", length: "arg", index: 0, checked: true},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			// The store to an unknown index keeps the load from being forwarded
+			input := ast.Block(ast.ArrayDecl("a", ast.NewArray(test.length)), ast.StoreIndex("a", "arg", 1), ast.Ret(ast.Index("a", test.index)))
+			retNode, err := NewGenerator(types.Bottom).Generate(input)
+			suite.Require().NoError(err)
+
+			load, ok := retNode.Expr().(*LoadNode)
+			suite.Require().True(ok)
+			_, checked := load.Index().(*BoundsCheckNode)
+			suite.Equal(test.checked, checked)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidArrays() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "not an array", input: ast.Block(ast.Ret(ast.Index("arg", 0))), error: "expected an array, got int"},
+		{name: "negative length", input: ast.Block(ast.ArrayDecl("a", ast.NewArray(-1)), ast.Ret(1)), error: "negative array length -1"},
+		{name: "out of bounds", input: ast.Block(ast.ArrayDecl("a", ast.NewArray(3)), ast.Ret(ast.Index("a", 3))), error: "index 3 out of bounds for length 3"},
+		{name: "negative index", input: ast.Block(ast.ArrayDecl("a", ast.NewArray("arg")), ast.Ret(ast.Index("a", -1))), error: "negative index -1"},
+		{name: "length of int", input: ast.Block(ast.Ret(ast.Len("arg"))), error: "expected an array, got int"},
+		{name: "array index", input: ast.Block(ast.ArrayDecl("a", ast.NewArray(1)), ast.Ret(ast.Index("a", "a"))), error: "expected int, got int[]"},
+		{name: "int to array", input: ast.Block(ast.ArrayDecl("a", 1), ast.Ret(1)), error: "expected int[], got int"},
+		{name: "assign length", input: ast.Block(ast.ArrayDecl("a", ast.NewArray(1)), &sast.AssignStmt{Lhs: ast.Len("a"), Rhs: ast.Num(2)}, ast.Ret(1)), error: "cannot assign to the length of an array"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestFloats() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "fold", input: ast.Block(ast.Ret(ast.Bin(0.1, "+", 0.2))), expected: "return 0.30000000000000004;"},
+		{name: "widen", input: ast.Block(ast.Ret(ast.Bin(1.5, "+", 2))), expected: "return 3.5;"},
+		{name: "infinity", input: ast.Block(ast.Ret(ast.Bin(1.0, "/", 0.0))), expected: "return +Inf;"},
+		{name: "nan", input: ast.Block(ast.Ret(ast.Bin(0.0, "/", 0.0))), expected: "return NaN;"},
+		{name: "negative zero", input: ast.Block(ast.Ret(ast.Bin(0.0, "*", -1))), expected: "return -0.0;"},
+		{name: "nan not equal", input: ast.Block(ast.Ret(ast.Bin(ast.Bin(0.0, "/", 0.0), "==", ast.Bin(0.0, "/", 0.0)))), expected: "return false;"},
+		{name: "add zero kept", input: ast.Block(ast.TypedDecl("flt", "f", "arg"), ast.Ret(ast.Bin("f", "+", 0.0))), expected: "return (flt(arg)+0.0);"},
+		{name: "sub zero", input: ast.Block(ast.TypedDecl("flt", "f", "arg"), ast.Ret(ast.Bin("f", "-", 0.0))), expected: "return flt(arg);"},
+		{name: "mul one", input: ast.Block(ast.TypedDecl("flt", "f", "arg"), ast.Ret(ast.Bin("f", "*", 1))), expected: "return flt(arg);"},
+		{name: "self compare kept", input: ast.Block(ast.TypedDecl("flt", "f", "arg"), ast.Ret(ast.Bin("f", "==", "f"))), expected: "return (flt(arg)==flt(arg));"},
+		{name: "self sub kept", input: ast.Block(ast.TypedDecl("flt", "f", "arg"), ast.Ret(ast.Bin("f", "-", "f"))), expected: "return (flt(arg)-flt(arg));"},
+		{name: "truncate", input: ast.Block(ast.Ret(ast.Call("int", -2.9))), expected: "return -2;"},
+		{name: "saturate", input: ast.Block(ast.Ret(ast.Call("int", 1e300))), expected: "return 9223372036854775807;"},
+		{name: "nan to int", input: ast.Block(ast.Ret(ast.Call("int", ast.Bin(0.0, "/", 0.0)))), expected: "return 0;"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidFloats() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "narrow", input: ast.Block(ast.Decl("i", 1.5), ast.Ret("i")), error: "expected int, got flt"},
+		{name: "not", input: ast.Block(ast.Ret(ast.Un("!", 1.5))), error: "expected bool or int, got flt"},
+		{name: "condition", input: ast.Block(ast.If(1.5, ast.Ret(1), nil), ast.Ret(2)), error: "expected bool or int, got flt"},
+		{name: "conversion arguments", input: ast.Block(ast.Ret(ast.Call("flt", 1, 2))), error: "flt expects 1 argument"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestBools() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "literal", input: ast.Block(ast.Ret("true")), expected: "return true;"},
+		{name: "not", input: ast.Block(ast.Ret(ast.Un("!", "false"))), expected: "return true;"},
+		{name: "equal", input: ast.Block(ast.Ret(ast.Bin("true", "==", "false"))), expected: "return false;"},
+		{name: "not equal", input: ast.Block(ast.Ret(ast.Bin(ast.Bin(1, "<", 2), "!=", "false"))), expected: "return true;"},
+		{name: "declared", input: ast.Block(ast.TypedDecl("bool", "b", ast.Bin("arg", "<", 3)), ast.Ret(ast.Un("!", "b"))), expected: "return (!(arg<3));"},
+		{name: "constant pred", input: ast.Block(ast.Decl("a", 1), ast.If("false", ast.Assign("a", 2), nil), ast.Ret("a")), expected: "return 1;"},
+		{name: "self compare", input: ast.Block(ast.TypedDecl("bool", "b", ast.Bin("arg", "<", 3)), ast.Ret(ast.Bin("b", "==", "b"))), expected: "return true;"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidBools() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "arithmetic", input: ast.Block(ast.Ret(ast.Bin(ast.Bin(1, "<", 2), "+", 3))), error: "expected int or flt, got bool"},
+		{name: "ordered", input: ast.Block(ast.Ret(ast.Bin("true", "<", "false"))), error: "expected int or flt, got bool"},
+		{name: "mixed equal", input: ast.Block(ast.Ret(ast.Bin(1, "==", "true"))), error: "expected int, got bool"},
+		{name: "assign int", input: ast.Block(ast.TypedDecl("bool", "b", 1), ast.Ret("b")), error: "expected bool, got int"},
+		{name: "assign bool", input: ast.Block(ast.Decl("i", "true"), ast.Ret("i")), error: "expected int, got bool"},
+		{name: "conversion", input: ast.Block(ast.Ret(ast.Call("int", "true"))), error: "expected int or flt, got bool"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestLogical() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "and", input: ast.Block(ast.Ret(ast.Bin(ast.Bin("arg", "<", 3), "&&", ast.Bin("arg", ">", 1)))), expected: "return Phi(Region13,(1<arg),false);"},
+		{name: "or", input: ast.Block(ast.Ret(ast.Bin(ast.Bin("arg", "<", 3), "||", ast.Bin("arg", ">", 5)))), expected: "return Phi(Region13,(5<arg),true);"},
+		{name: "int rhs", input: ast.Block(ast.Ret(ast.Bin("true", "&&", "arg"))), expected: "return (!(arg==0));"},
+		{name: "false and", input: ast.Block(ast.Ret(ast.Bin(0, "&&", ast.Call("f", "arg")))), expected: "return false;"},
+		{name: "true or", input: ast.Block(ast.Ret(ast.Bin(1, "||", ast.Call("f", "arg")))), expected: "return true;"},
+		{name: "condition", input: ast.Block(ast.Decl("a", 1), ast.If(ast.Bin("arg", "&&", "a"), ast.Assign("a", 2), nil), ast.Ret("a")), expected: "return Phi(Region22,2,1);"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidLogical() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "flt lhs", input: ast.Block(ast.Ret(ast.Bin(1.5, "&&", "true"))), error: "expected bool or int, got flt"},
+		{name: "flt rhs", input: ast.Block(ast.Ret(ast.Bin("arg", "||", 1.5))), error: "expected bool or int, got flt"},
+		{name: "arithmetic", input: ast.Block(ast.Ret(ast.Bin(ast.Paren(ast.Bin("arg", "&&", "arg")), "+", 1))), error: "expected int or flt, got bool"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestBitwise() {
+	subTests := []struct {
+		name     string
+		input    *sast.BlockStmt
+		expected string
+	}{
+		{name: "and", input: ast.Block(ast.Ret(ast.Bin(12, "&", 10))), expected: "return 8;"},
+		{name: "or", input: ast.Block(ast.Ret(ast.Bin(12, "|", 10))), expected: "return 14;"},
+		{name: "xor", input: ast.Block(ast.Ret(ast.Bin(12, "^", 10))), expected: "return 6;"},
+		{name: "shl", input: ast.Block(ast.Ret(ast.Bin(1, "<<", 65))), expected: "return 2;"},
+		{name: "ashr", input: ast.Block(ast.Ret(ast.Bin(-8, ">>", 1))), expected: "return -4;"},
+		{name: "lshr", input: ast.Block(ast.Ret(ast.Bin(-1, ">>>", 60))), expected: "return 15;"},
+		{name: "complement", input: ast.Block(ast.Ret(ast.Un("^", 5))), expected: "return -6;"},
+		{name: "and zero", input: ast.Block(ast.Ret(ast.Bin("arg", "&", 0))), expected: "return 0;"},
+		{name: "and ones", input: ast.Block(ast.Ret(ast.Bin("arg", "&", -1))), expected: "return arg;"},
+		{name: "and self", input: ast.Block(ast.Ret(ast.Bin("arg", "&", "arg"))), expected: "return arg;"},
+		{name: "or zero", input: ast.Block(ast.Ret(ast.Bin(0, "|", "arg"))), expected: "return arg;"},
+		{name: "or ones", input: ast.Block(ast.Ret(ast.Bin("arg", "|", -1))), expected: "return -1;"},
+		{name: "xor self", input: ast.Block(ast.Ret(ast.Bin("arg", "^", "arg"))), expected: "return 0;"},
+		{name: "xor zero", input: ast.Block(ast.Ret(ast.Bin("arg", "^", 0))), expected: "return arg;"},
+		{name: "shl zero", input: ast.Block(ast.Ret(ast.Bin("arg", "<<", 0))), expected: "return arg;"},
+		{name: "ashr wrapped zero", input: ast.Block(ast.Ret(ast.Bin("arg", ">>", 64))), expected: "return arg;"},
+		{name: "lshr zero", input: ast.Block(ast.Ret(ast.Bin("arg", ">>>", 0))), expected: "return arg;"},
+		{name: "lshr kept", input: ast.Block(ast.Ret(ast.Bin("arg", ">>>", 1))), expected: "return (arg>>>1);"},
+		{name: "shift of zero", input: ast.Block(ast.Ret(ast.Bin(0, "<<", "arg"))), expected: "return 0;"},
+		{name: "constant swapped", input: ast.Block(ast.Ret(ast.Bin(3, "&", "arg"))), expected: "return (arg&3);"},
+		{name: "constants combined", input: ast.Block(ast.Ret(ast.Bin(ast.Bin("arg", "|", 1), "|", 2))), expected: "return (arg|3);"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			retNode, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.Require().NoError(err)
+			suite.Equal(test.expected, ToString(retNode))
+		})
+	}
+}
+
+func (suite *GeneratorTestSuite) TestInvalidBitwise() {
+	subTests := []struct {
+		name  string
+		input *sast.BlockStmt
+		error string
+	}{
+		{name: "flt", input: ast.Block(ast.Ret(ast.Bin(1.5, "&", 1))), error: "expected int, got flt"},
+		{name: "bool", input: ast.Block(ast.Ret(ast.Bin(1, "<<", "true"))), error: "expected int, got bool"},
+		{name: "complement flt", input: ast.Block(ast.Ret(ast.Un("^", 1.5))), error: "expected int, got flt"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, err := NewGenerator(types.Bottom).Generate(test.input)
+			suite.ErrorContains(err, test.error)
+		})
+	}
+}
+
 func TestGenerator(t *testing.T) {
 	suite.Run(t, new(GeneratorTestSuite))
 }
@@ -35,7 +35,8 @@ func Visualize(generator *Generator) string {
 
 func quoteName(n Node) string {
 	name := UniqueName(n)
-	if name[0] == '$' {
+	// Memory phis are named after the memory in the scope, e.g. Phi_$1
+	if strings.ContainsRune(name, '$') {
 		return "\"" + name + "\""
 	}
 	return name
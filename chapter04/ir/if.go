@@ -0,0 +1,61 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+type IfNode struct {
+	baseNode
+}
+
+func NewIfNode(control Node, pred Node) *IfNode {
+	return initBaseNode(&IfNode{}, control, pred)
+}
+
+func (i *IfNode) Control() Node { return In(i, 0) }
+func (i *IfNode) Pred() Node    { return In(i, 1) }
+
+func (i *IfNode) IsControl() bool      { return true }
+func (i *IfNode) GraphicLabel() string { return "If" }
+func (i *IfNode) label() string        { return "If" }
+
+func (i *IfNode) multinode() {}
+
+// compute returns a tuple of the true and false branches. A branch that can never be taken is Top.
+func (i *IfNode) compute() (types.Type, error) {
+	if i.Control() == nil || Type(i.Control()) != types.Control {
+		return types.NewTuple(types.Top, types.Top), nil
+	}
+
+	pred, ok := constantCondition(i.Pred())
+	if !ok {
+		return types.NewTuple(types.Control, types.Control), nil
+	}
+	if pred {
+		return types.NewTuple(types.Control, types.Top), nil
+	}
+	return types.NewTuple(types.Top, types.Control), nil
+}
+
+// constantCondition returns the value of a condition that is known at compile time. Ints are true when they are not zero, and references when they are not null.
+func constantCondition(n Node) (bool, bool) {
+	switch t := Type(n).(type) {
+	case *types.Int:
+		return t.Value != 0, t.Constant()
+	case *types.Bool:
+		return t.Value, t.Constant()
+	case *types.MemPtr:
+		return t != types.Null, !t.Nullable || t == types.Null
+	}
+	return false, false
+}
+
+func (i *IfNode) idealize() (Node, error) { return nil, nil }
+
+func (i *IfNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("if( ")
+	toString(i.Pred(), sb)
+	sb.WriteString(" )")
+}
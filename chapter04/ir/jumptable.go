@@ -0,0 +1,64 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// JumpTableNode branches to one of many controls depending on an int, as a dense switch does. Projection i is taken when the selector equals Values[i], and the last projection is taken when it equals none of them. Backends can index a table with the selector minus the smallest value.
+type JumpTableNode struct {
+	baseNode
+	Values []int
+}
+
+func NewJumpTableNode(control Node, selector Node, values []int) *JumpTableNode {
+	return initBaseNode(&JumpTableNode{Values: values}, control, selector)
+}
+
+func (j *JumpTableNode) Control() Node  { return In(j, 0) }
+func (j *JumpTableNode) Selector() Node { return In(j, 1) }
+
+func (j *JumpTableNode) IsControl() bool      { return true }
+func (j *JumpTableNode) GraphicLabel() string { return "JumpTable" }
+func (j *JumpTableNode) label() string        { return "JumpTable" }
+
+func (j *JumpTableNode) multinode() {}
+
+// compute returns a tuple with a control for every value and the default. When the selector is a constant only its branch can be taken, the others are Top.
+func (j *JumpTableNode) compute() (types.Type, error) {
+	branches := make([]types.Type, len(j.Values)+1)
+	if j.Control() == nil || Type(j.Control()) != types.Control {
+		for i := range branches {
+			branches[i] = types.Top
+		}
+		return types.NewTuple(branches...), nil
+	}
+
+	selector, ok := Type(j.Selector()).(*types.Int)
+	if !ok || !selector.Constant() {
+		for i := range branches {
+			branches[i] = types.Control
+		}
+		return types.NewTuple(branches...), nil
+	}
+	taken := len(j.Values)
+	for i, v := range j.Values {
+		if v == selector.Value {
+			taken = i
+		}
+	}
+	for i := range branches {
+		branches[i] = types.Top
+	}
+	branches[taken] = types.Control
+	return types.NewTuple(branches...), nil
+}
+
+func (j *JumpTableNode) idealize() (Node, error) { return nil, nil }
+
+func (j *JumpTableNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("switch( ")
+	toString(j.Selector(), sb)
+	sb.WriteString(" )")
+}
@@ -0,0 +1,150 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// LoadNode loads the value of a field. Its inputs are the memory of the alias class of the field, the struct reference and, for array elements, the index.
+type LoadNode struct {
+	baseNode
+	field *types.Field
+}
+
+func NewLoadNode(field *types.Field, mem Node, ptr Node) *LoadNode {
+	return initBaseNode(&LoadNode{field: field}, mem, ptr)
+}
+
+// NewArrayLoadNode creates a load of the array element at the index.
+func NewArrayLoadNode(field *types.Field, mem Node, ptr Node, index Node) *LoadNode {
+	return initBaseNode(&LoadNode{field: field}, mem, ptr, index)
+}
+
+func (l *LoadNode) Mem() Node { return In(l, 0) }
+func (l *LoadNode) Ptr() Node { return In(l, 1) }
+
+// Index returns the index of the loaded array element, or nil for struct fields.
+func (l *LoadNode) Index() Node {
+	if NumOfIns(l) < 3 {
+		return nil
+	}
+	return In(l, 2)
+}
+
+func (l *LoadNode) IsControl() bool      { return false }
+func (l *LoadNode) GraphicLabel() string { return "." + l.field.Name }
+func (l *LoadNode) label() string        { return "Load_" + l.field.Name }
+
+// compute folds the length and the elements at constant indices of string literals, whose bytes never change.
+func (l *LoadNode) compute() (types.Type, error) {
+	p, ok := Type(l.Ptr()).(*types.MemPtr)
+	if !ok || !p.Constant() || p == types.Null {
+		return l.field.Type, nil
+	}
+	switch l.field.Name {
+	case types.ArrayLength:
+		return types.NewInt(len(p.Value)), nil
+	case types.ArrayElems:
+		if i, ok := Type(l.Index()).(*types.Int); ok && i.Constant() && i.Value >= 0 && i.Value < len(p.Value) {
+			return types.NewInt(int(p.Value[i.Value])), nil
+		}
+	}
+	return l.field.Type, nil
+}
+
+func (l *LoadNode) idealize() (Node, error) {
+	// Forwarding a value would drop the bounds check of the index
+	if _, ok := l.Index().(*BoundsCheckNode); ok {
+		return nil, nil
+	}
+
+	if v := l.forward(l.Mem()); v != nil {
+		return v, nil
+	}
+
+	// When every path into a merge stores to the same struct, the load becomes a phi of the stored values
+	phi, ok := l.Mem().(*PhiNode)
+	if !ok || phi.inProgress() {
+		return nil, nil
+	}
+	if _, isLoop := phi.Region().(*LoopNode); isLoop {
+		return nil, nil
+	}
+	var values []Node
+	for _, mem := range Ins(phi)[1:] {
+		v := l.forward(mem)
+		if v == nil {
+			return nil, nil
+		}
+		values = append(values, v)
+	}
+	return NewPhiNode(l.field.Name, l.field.Type, phi.Region(), values...), nil
+}
+
+// forward returns the value of the store to the same address that mem is the result of, or nil if it is unknown. Stores to other allocations or other indices in the same alias class are skipped, since they never write the loaded memory.
+func (l *LoadNode) forward(mem Node) Node {
+	for {
+		s, ok := mem.(*StoreNode)
+		if !ok {
+			return nil
+		}
+		// A store without an index writes all the elements of an array
+		if s.Ptr() == l.Ptr() && (s.Index() == nil || sameIndex(s.Index(), l.Index())) {
+			return s.Value()
+		}
+		if !distinctAllocations(s.Ptr(), l.Ptr()) && !distinctIndices(s.Index(), l.Index()) {
+			return nil
+		}
+		mem = s.Mem()
+	}
+}
+
+// distinctAllocations returns true if both references are known to come from different allocations.
+func distinctAllocations(a Node, b Node) bool {
+	_, aIsNew := a.(*NewNode)
+	_, bIsNew := b.(*NewNode)
+	return aIsNew && bIsNew && a != b
+}
+
+// sameIndex returns true if both indices are known to be equal.
+func sameIndex(a Node, b Node) bool {
+	if a == b {
+		return true
+	}
+	aType, aOk := Type(a).(*types.Int)
+	bType, bOk := Type(b).(*types.Int)
+	return aOk && bOk && aType.Constant() && bType.Constant() && aType.Value == bType.Value
+}
+
+// distinctIndices returns true if both indices are known to be different.
+func distinctIndices(a Node, b Node) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	aType, aOk := Type(a).(*types.Int)
+	bType, bOk := Type(b).(*types.Int)
+	return aOk && bOk && aType.Constant() && bType.Constant() && aType.Value != bType.Value
+}
+
+func (l *LoadNode) toStringInternal(sb *strings.Builder) {
+	toString(l.Ptr(), sb)
+	toStringField(l.field, l.Index(), sb)
+}
+
+// toStringField prints the access of a field, which is .f for struct fields, # for the length of an array and [i] for array elements.
+func toStringField(f *types.Field, index Node, sb *strings.Builder) {
+	switch f.Name {
+	case types.ArrayLength:
+		sb.WriteString("#")
+	case types.ArrayElems:
+		sb.WriteString("[")
+		if index != nil {
+			toString(index, sb)
+		}
+		sb.WriteString("]")
+	default:
+		sb.WriteString(".")
+		sb.WriteString(f.Name)
+	}
+}
@@ -0,0 +1,37 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// LoopNode is the region at the head of a loop. Input 1 is the entry control and input 2 is the back edge, which is only set once the loop body was generated.
+type LoopNode struct {
+	RegionNode
+	inProgress bool
+}
+
+func NewLoopNode(entry Node) *LoopNode {
+	return initBaseNode(&LoopNode{inProgress: true}, nil, entry, nil)
+}
+
+func (l *LoopNode) Entry() Node { return In(l, 1) }
+func (l *LoopNode) Back() Node  { return In(l, 2) }
+
+func (l *LoopNode) GraphicLabel() string { return "Loop" }
+func (l *LoopNode) label() string        { return "Loop" }
+
+func (l *LoopNode) compute() (types.Type, error) {
+	// A loop in dead code is dead
+	if l.Entry() == nil {
+		return types.Top, nil
+	}
+	return types.Control, nil
+}
+
+func (l *LoopNode) idealize() (Node, error) { return nil, nil }
+
+func (l *LoopNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString(UniqueName(l))
+}
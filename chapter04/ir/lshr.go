@@ -0,0 +1,48 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// LShrNode is the logical shift right, which shifts in zeros. Only the lowest 6 bits of the rhs are used, as in Java.
+type LShrNode struct {
+	binaryNode
+}
+
+func NewLShrNode(lhs Node, rhs Node) *LShrNode {
+	return initBinaryNode(&LShrNode{}, lhs, rhs)
+}
+
+func (l *LShrNode) GraphicLabel() string { return ">>>" }
+func (l *LShrNode) label() string        { return "LShr" }
+
+func (l *LShrNode) compute() (types.Type, error) {
+	// 0>>>x=>0
+	if isIntConstant(l.Lhs(), 0) {
+		return types.NewInt(0), nil
+	}
+	lhs, lOk := intConstant(l.Lhs())
+	rhs, rOk := shiftAmount(l.Rhs())
+	if !lOk || !rOk {
+		return types.Bottom, nil
+	}
+	return types.NewInt(int(uint64(lhs) >> rhs)), nil
+}
+
+func (l *LShrNode) idealize() (Node, error) {
+	// x>>>0=>x
+	if rhs, ok := shiftAmount(l.Rhs()); ok && rhs == 0 {
+		return l.Lhs(), nil
+	}
+	return nil, nil
+}
+
+func (l *LShrNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(l.Lhs(), sb)
+	sb.WriteString(">>>")
+	toString(l.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -24,6 +24,10 @@ func (m *MinusNode) compute() (types.Type, error) {
 		if typ.Constant() {
 			return types.NewInt(-typ.Value), nil
 		}
+		// The negation of the smallest value of a signed sized int does not fit in its width until the generator wraps it
+		if typ.Sized() {
+			return types.IntBottom, nil
+		}
 		return typ, nil
 	}
 
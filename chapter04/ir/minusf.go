@@ -0,0 +1,43 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// MinusFNode negates a float, which flips its sign bit.
+type MinusFNode struct {
+	baseNode
+}
+
+func NewMinusFNode(value Node) *MinusFNode {
+	return initBaseNode(&MinusFNode{}, value)
+}
+
+func (m *MinusFNode) Value() Node { return In(m, 0) }
+
+func (m *MinusFNode) IsControl() bool      { return false }
+func (m *MinusFNode) GraphicLabel() string { return "-" }
+func (m *MinusFNode) label() string        { return "MinusF" }
+
+func (m *MinusFNode) compute() (types.Type, error) {
+	if v, ok := fltConstant(m.Value()); ok {
+		return types.NewFlt(-v), nil
+	}
+	return types.FltBottom, nil
+}
+
+func (m *MinusFNode) idealize() (Node, error) {
+	// -(-x) => x
+	if n, ok := m.Value().(*MinusFNode); ok {
+		return n.Value(), nil
+	}
+	return nil, nil
+}
+
+func (m *MinusFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(-")
+	toString(m.Value(), sb)
+	sb.WriteString(")")
+}
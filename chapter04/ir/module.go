@@ -0,0 +1,130 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// Module is a source file of a program. The main module holds the statements of the program, while imported modules only declare functions and structs. A module sees its own declarations and the exported declarations of the modules it imports, but not the modules those import.
+type Module struct {
+	// Path is the import path of the module, empty for the main module
+	Path string
+	Body *ast.BlockStmt
+	// Imports are the imported modules by import path. Imports must not form a cycle.
+	Imports map[string]*Module
+	// Result is the declared type of the values returned by the main module, like the result of a function. Without it the main module may return any int or flt.
+	Result ast.TypeExpr
+
+	// funcs and structs are the names visible in the module
+	funcs   map[string]*FunNode
+	structs map[string]*types.Struct
+}
+
+// dependencies returns the module and all the modules it imports directly or indirectly, where every module comes after the modules it imports. The module itself is last.
+func (m *Module) dependencies() []*Module {
+	var modules []*Module
+	visited := map[*Module]bool{}
+	var visit func(m *Module)
+	visit = func(m *Module) {
+		visited[m] = true
+		for _, imp := range imports(m.Body) {
+			if dep, ok := m.Imports[importPath(imp)]; ok && !visited[dep] {
+				visit(dep)
+			}
+		}
+		modules = append(modules, m)
+	}
+	visit(m)
+	return modules
+}
+
+// imports returns the import declarations at the top level of the block.
+func imports(b *ast.BlockStmt) []*ast.ImportDecl {
+	var decls []*ast.ImportDecl
+	for _, stmt := range b.List {
+		if imp, ok := stmt.(*ast.ImportDecl); ok {
+			decls = append(decls, imp)
+		}
+	}
+	return decls
+}
+
+// importPath returns the path of an import declaration, or the literal as written if it is not a valid string.
+func importPath(imp *ast.ImportDecl) string {
+	path, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return imp.Path.Value
+	}
+	return path
+}
+
+// checkModule returns an error if an imported module has statements other than imports and declarations of functions and structs.
+func checkModule(m *Module) error {
+	for _, stmt := range m.Body.List {
+		switch stmt.(type) {
+		case *ast.ImportDecl, *ast.FuncDecl, *ast.StructDecl:
+		default:
+			return computeError(stmt, fmt.Sprintf("module %s can only declare functions and structs", m.Path))
+		}
+	}
+	return nil
+}
+
+// enter makes the names of the module visible to the generated code.
+func (g *Generator) enter(m *Module) {
+	if m.funcs == nil {
+		m.funcs = map[string]*FunNode{}
+		m.structs = map[string]*types.Struct{}
+	}
+	g.funcs, g.structs = m.funcs, m.structs
+}
+
+// importStructs makes the structs exported by the module of an import in m visible in m. The imported module must already be declared.
+func (g *Generator) importStructs(m *Module, imp *ast.ImportDecl) error {
+	return g.importDecls(m, imp, func(stmt ast.Stmt) error {
+		d, ok := stmt.(*ast.StructDecl)
+		if !ok || !d.Export.IsValid() {
+			return nil
+		}
+		return importName(m.structs, d.Name.Name, g.structDecls[d], imp)
+	})
+}
+
+// importFuncs makes the functions exported by the module of an import in m visible in m. The imported module must already be declared.
+func (g *Generator) importFuncs(m *Module, imp *ast.ImportDecl) error {
+	return g.importDecls(m, imp, func(stmt ast.Stmt) error {
+		d, ok := stmt.(*ast.FuncDecl)
+		if !ok || !d.Export.IsValid() {
+			return nil
+		}
+		return importName(m.funcs, d.Name.Name, g.funcDecls[d], imp)
+	})
+}
+
+// importDecls calls f with every top level statement of the module of an import in m.
+func (g *Generator) importDecls(m *Module, imp *ast.ImportDecl, f func(stmt ast.Stmt) error) error {
+	dep, ok := m.Imports[importPath(imp)]
+	if !ok {
+		return computeError(imp.Path, "unknown module "+imp.Path.Value)
+	}
+	g.imports[imp] = true
+	for _, stmt := range dep.Body.List {
+		err := f(stmt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importName makes an imported declaration visible under its name. Importing the same declaration twice is allowed, but a name cannot refer to two declarations.
+func importName[T comparable](names map[string]T, name string, decl T, imp *ast.ImportDecl) error {
+	if old, ok := names[name]; ok && old != decl {
+		return computeError(imp.Path, fmt.Sprintf("cannot import %s from %s: name already defined", name, imp.Path.Value))
+	}
+	names[name] = decl
+	return nil
+}
@@ -35,7 +35,7 @@ func (m *MulNode) compute() (types.Type, error) {
 }
 
 func (m *MulNode) idealize() (Node, error) {
-	if rType, ok := Type(m.Rhs()).(*types.Int); ok && rType.Value == 1 {
+	if rType, ok := Type(m.Rhs()).(*types.Int); ok && rType.Constant() && rType.Value == 1 {
 		return m.Lhs(), nil
 	}
 
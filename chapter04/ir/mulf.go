@@ -0,0 +1,50 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// MulFNode multiplies two floats.
+type MulFNode struct {
+	binaryNode
+}
+
+func NewMulFNode(lhs Node, rhs Node) *MulFNode {
+	return initBinaryNode(&MulFNode{}, lhs, rhs)
+}
+
+func (m *MulFNode) GraphicLabel() string { return "*" }
+func (m *MulFNode) label() string        { return "MulF" }
+
+func (m *MulFNode) compute() (types.Type, error) {
+	lhs, lOk := fltConstant(m.Lhs())
+	rhs, rOk := fltConstant(m.Rhs())
+	if lOk && rOk {
+		return types.NewFlt(lhs * rhs), nil
+	}
+	return types.FltBottom, nil
+}
+
+// idealize does not fold x*0.0 to 0.0, since it is NaN for infinities and NaN and -0.0 for negative x.
+func (m *MulFNode) idealize() (Node, error) {
+	// x * 1.0 => x
+	if isFltConstant(m.Rhs(), 1) {
+		return m.Lhs(), nil
+	}
+	// Move constants to the rhs
+	if Type(m.Lhs()).Constant() && !Type(m.Rhs()).Constant() {
+		m.swap()
+		return m, nil
+	}
+	return nil, nil
+}
+
+func (m *MulFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(m.Lhs(), sb)
+	sb.WriteString("*")
+	toString(m.Rhs(), sb)
+	sb.WriteString(")")
+}
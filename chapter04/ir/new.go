@@ -0,0 +1,29 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// NewNode allocates a struct. Its input is the control and its result is a reference to the new struct.
+type NewNode struct {
+	baseNode
+	ptr *types.MemPtr
+}
+
+func NewNewNode(control Node, ptr *types.MemPtr) *NewNode {
+	return initBaseNode(&NewNode{ptr: ptr}, control)
+}
+
+func (n *NewNode) IsControl() bool      { return false }
+func (n *NewNode) GraphicLabel() string { return "new " + n.ptr.Struct.Name }
+func (n *NewNode) label() string        { return "New" }
+
+func (n *NewNode) compute() (types.Type, error) { return n.ptr, nil }
+func (n *NewNode) idealize() (Node, error)      { return nil, nil }
+
+func (n *NewNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("new ")
+	sb.WriteString(n.ptr.Struct.Name)
+}
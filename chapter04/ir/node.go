@@ -2,11 +2,12 @@ package ir
 
 import (
 	"fmt"
-	"go/ast"
+	"go/token"
 	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
 	"github.com/pkg/errors"
 )
@@ -16,8 +17,12 @@ var DisablePeephole = false
 var nodeID = 0
 
 func computeError(n ast.Node, msg string) *ASTError {
+	return computeErrorAt(n.Pos(), msg)
+}
+
+func computeErrorAt(pos token.Pos, msg string) *ASTError {
 	internal := errors.New("Compute error: " + msg)
-	return &ASTError{error: internal, Pos: n.Pos()}
+	return &ASTError{error: internal, Pos: pos}
 }
 
 // Node is the interface every node type must implement. In order to avoid duplicate code, nodes should embed `baseNode`.
@@ -193,7 +198,7 @@ func peephole(n Node) (Node, error) {
 	}
 
 	var opt Node
-	if _, ok := n.(*ConstantNode); !ok && Type(n).Constant() {
+	if _, ok := n.(*ConstantNode); !ok && !n.IsControl() && Type(n).Constant() {
 		opt = NewConstantNode(typ)
 	} else {
 		var err error
@@ -237,6 +242,27 @@ func replace(old Node, new Node) error {
 	return nil
 }
 
+// subsume replaces every use of old with new, and kills old.
+func subsume(old Node, new Node) error {
+	pin(old)
+	for NumOfOuts(old) > 0 {
+		out := Outs(old)[0]
+		for i, in := range Ins(out) {
+			if in == old {
+				err := setIn(out, i, new)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	unpin(old)
+	if new == nil {
+		return kill(old)
+	}
+	return replace(old, new)
+}
+
 func ToString(n Node) string {
 	sb := &strings.Builder{}
 	toString(n, sb)
@@ -27,19 +27,23 @@ func (n *NotNode) idealize() (Node, error) {
 	return nil, nil
 }
 
+// compute negates a bool. Ints are true when they are not zero, so !x is x == 0 for ints, and references are true when they are not null.
 func (n *NotNode) compute() (types.Type, error) {
-	t, ok := Type(n.value()).(*types.Int)
-	if !ok {
-		return types.Bottom, nil
-	}
-	if !t.Constant() {
-		return t, nil
-	}
-
-	if t.Value == 0 {
-		return types.NewInt(1), nil
+	switch t := Type(n.value()).(type) {
+	case *types.MemPtr:
+		if pred, ok := constantCondition(n.value()); ok {
+			return types.NewBool(!pred), nil
+		}
+	case *types.Int:
+		if t.Constant() {
+			return types.NewBool(t.Value == 0), nil
+		}
+	case *types.Bool:
+		if t.Constant() {
+			return types.NewBool(!t.Value), nil
+		}
 	}
-	return types.NewInt(0), nil
+	return types.BoolBottom, nil
 }
 
 func (n *NotNode) label() string        { return "Not" }
@@ -0,0 +1,62 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+type OrNode struct {
+	binaryNode
+}
+
+func NewOrNode(lhs Node, rhs Node) *OrNode {
+	return initBinaryNode(&OrNode{}, lhs, rhs)
+}
+
+func (o *OrNode) GraphicLabel() string { return "|" }
+func (o *OrNode) label() string        { return "Or" }
+
+func (o *OrNode) compute() (types.Type, error) {
+	// x|-1=>-1
+	if isIntConstant(o.Lhs(), -1) || isIntConstant(o.Rhs(), -1) {
+		return types.NewInt(-1), nil
+	}
+	lhs, lOk := intConstant(o.Lhs())
+	rhs, rOk := intConstant(o.Rhs())
+	if !lOk || !rOk {
+		return types.Bottom, nil
+	}
+	return types.NewInt(lhs | rhs), nil
+}
+
+func (o *OrNode) idealize() (Node, error) {
+	// x|0=>x, x|x=>x
+	if isIntConstant(o.Rhs(), 0) || o.Lhs() == o.Rhs() {
+		return o.Lhs(), nil
+	}
+
+	if Type(o.Lhs()).Constant() && !Type(o.Rhs()).Constant() {
+		o.swap()
+		return o, nil
+	}
+
+	// (x|c1)|c2=>x|(c1|c2)
+	if l, ok := o.Lhs().(*OrNode); ok && Type(l.Rhs()).Constant() && Type(o.Rhs()).Constant() {
+		rhs, err := peephole(NewOrNode(l.Rhs(), o.Rhs()))
+		if err != nil {
+			return nil, err
+		}
+		return NewOrNode(l.Lhs(), rhs), nil
+	}
+
+	return nil, nil
+}
+
+func (o *OrNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(o.Lhs(), sb)
+	sb.WriteString("|")
+	toString(o.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,107 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// PhiNode selects a value based on the control input of its region. Input 0 is the region, input i matches input i of the region.
+type PhiNode struct {
+	baseNode
+	name     string
+	declared types.Type
+
+	// printing is set while the phi is printed, so loop phis that depend on themselves are not printed forever
+	printing bool
+}
+
+func NewPhiNode(name string, declared types.Type, region Node, values ...Node) *PhiNode {
+	return initBaseNode(&PhiNode{name: name, declared: declared}, append([]Node{region}, values...)...)
+}
+
+func (p *PhiNode) Region() Node { return In(p, 0) }
+
+func (p *PhiNode) IsControl() bool      { return false }
+func (p *PhiNode) GraphicLabel() string { return "&phi;_" + p.name }
+func (p *PhiNode) label() string        { return "Phi_" + p.name }
+
+// inProgress is true while the phi is at the head of a loop whose back edge was not generated yet.
+func (p *PhiNode) inProgress() bool {
+	l, ok := p.Region().(*LoopNode)
+	return ok && l.inProgress
+}
+
+func (p *PhiNode) compute() (types.Type, error) {
+	// The back edge is unknown, so only the declared type is known
+	if p.inProgress() {
+		return p.declared, nil
+	}
+
+	var typ types.Type = types.Top
+	for _, in := range Ins(p)[1:] {
+		// Dead back edges and self references do not add values
+		if in != nil && in != Node(p) {
+			typ = typ.Meet(Type(in))
+		}
+	}
+	return typ, nil
+}
+
+func (p *PhiNode) idealize() (Node, error) {
+	if p.inProgress() {
+		return nil, nil
+	}
+
+	// Remove a phi whose inputs are all the same node, ignoring dead back edges and self references
+	if same := p.sameInput(func(n Node) Node { return n }); same != nil {
+		return same, nil
+	}
+	// Casts do not change the value, so a phi of a reference and its casts is the reference
+	return p.sameInput(uncast), nil
+}
+
+// sameInput returns the node all the inputs are the same as after applying f, or nil if they differ.
+func (p *PhiNode) sameInput(f func(Node) Node) Node {
+	var same Node
+	for _, in := range Ins(p)[1:] {
+		if in == nil || in == Node(p) {
+			continue
+		}
+		in = f(in)
+		if in == same {
+			continue
+		}
+		if same != nil {
+			return nil
+		}
+		same = in
+	}
+	return same
+}
+
+// uncast returns the value of a cast, or n if it is not a cast.
+func uncast(n Node) Node {
+	if c, ok := n.(*CastNode); ok {
+		return c.Value()
+	}
+	return n
+}
+
+func (p *PhiNode) toStringInternal(sb *strings.Builder) {
+	if p.printing {
+		sb.WriteString(p.label())
+		return
+	}
+	p.printing = true
+	defer func() { p.printing = false }()
+
+	sb.WriteString("Phi(")
+	for i, in := range Ins(p) {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		toString(in, sb)
+	}
+	sb.WriteString(")")
+}
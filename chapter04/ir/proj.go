@@ -16,9 +16,38 @@ func NewProjNode(control MultiNode, i int, label string) *ProjNode {
 	return initBaseNode(&ProjNode{i: i, s: label}, control)
 }
 
-func (p *ProjNode) control() Node           { return In(p, 0) }
-func (p *ProjNode) IsControl() bool         { return p.i == 0 }
-func (p *ProjNode) idealize() (Node, error) { return nil, nil }
+func (p *ProjNode) control() Node { return In(p, 0) }
+
+func (p *ProjNode) IsControl() bool {
+	switch p.control().(type) {
+	case *IfNode, *JumpTableNode:
+		return true
+	}
+	return p.i == 0
+}
+
+func (p *ProjNode) idealize() (Node, error) {
+	// When the other branches of an if or a jump table can never be taken, this branch is just the control of the branch
+	var control Node
+	switch b := p.control().(type) {
+	case *IfNode:
+		control = b.Control()
+	case *JumpTableNode:
+		control = b.Control()
+	default:
+		return nil, nil
+	}
+	t, ok := Type(p.control()).(*types.Tuple)
+	if !ok || t.Types[p.i] != types.Control {
+		return nil, nil
+	}
+	for i, typ := range t.Types {
+		if i != p.i && typ != types.Top {
+			return nil, nil
+		}
+	}
+	return control, nil
+}
 
 func (p *ProjNode) compute() (types.Type, error) {
 	if t, ok := Type(p.control()).(*types.Tuple); ok {
@@ -27,6 +56,14 @@ func (p *ProjNode) compute() (types.Type, error) {
 	return types.Bottom, nil
 }
 
-func (p *ProjNode) label() string                        { return p.s }
-func (p *ProjNode) GraphicLabel() string                 { return p.s }
-func (p *ProjNode) toStringInternal(sb *strings.Builder) { sb.WriteString(p.s) }
+func (p *ProjNode) label() string        { return p.s }
+func (p *ProjNode) GraphicLabel() string { return p.s }
+
+func (p *ProjNode) toStringInternal(sb *strings.Builder) {
+	// The returned value of a call is printed as the call itself
+	if c, ok := p.control().(*CallEndNode); ok && !p.IsControl() {
+		toString(c, sb)
+		return
+	}
+	sb.WriteString(p.s)
+}
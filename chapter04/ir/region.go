@@ -0,0 +1,43 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// RegionNode merges control flow. Input 0 is always nil, the rest are the merged control inputs.
+type RegionNode struct {
+	baseNode
+}
+
+func NewRegionNode(controls ...Node) *RegionNode {
+	return initBaseNode(&RegionNode{}, append([]Node{nil}, controls...)...)
+}
+
+func (r *RegionNode) IsControl() bool      { return true }
+func (r *RegionNode) GraphicLabel() string { return "Region" }
+func (r *RegionNode) label() string        { return "Region" }
+
+func (r *RegionNode) compute() (types.Type, error) { return types.Control, nil }
+
+func (r *RegionNode) idealize() (Node, error) {
+	// A region with a single control input and no phis is just that input
+	if NumOfIns(r) == 2 && !r.hasPhi() {
+		return In(r, 1), nil
+	}
+	return nil, nil
+}
+
+func (r *RegionNode) hasPhi() bool {
+	for _, out := range Outs(r) {
+		if _, ok := out.(*PhiNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RegionNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString(UniqueName(r))
+}
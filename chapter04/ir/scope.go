@@ -1,7 +1,10 @@
 package ir
 
 import (
+	"maps"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
 	"github.com/pkg/errors"
@@ -15,6 +18,13 @@ const Arg0 = "arg"
 type ScopeNode struct {
 	Scopes []symbolTable
 	baseNode
+
+	// decls are the declared types of the names, indexed by input
+	decls []types.Type
+	// finals are true for names that cannot be assigned after their definition, indexed by input
+	finals []bool
+	// uninit are true for names that are not assigned on every path to the current control, indexed by input
+	uninit []bool
 }
 
 func NewScopeNode() *ScopeNode {
@@ -53,16 +63,60 @@ func (s *ScopeNode) toStringInternal(sb *strings.Builder) {
 func (s *ScopeNode) Control() Node                 { return In(s, 0) }
 func (s *ScopeNode) SetControl(control Node) error { return setIn(s, 0, control) }
 
-func (s *ScopeNode) Define(name string, n Node) error {
+func (s *ScopeNode) Define(name string, declared types.Type, n Node) error {
 	table := s.Scopes[len(s.Scopes)-1]
 	if _, ok := table[name]; ok {
-		return errors.Errorf("Cannot define a name that already exists: %s", name)
+		return errors.Errorf("name already defined: %s", name)
 	}
 	table[name] = NumOfIns(s)
 	addIn(s, n)
+	s.decls = append(s.decls, declared)
+	s.finals = append(s.finals, false)
+	s.uninit = append(s.uninit, false)
+	return nil
+}
+
+// DefineUninit defines a name that has no value yet. n is a placeholder which must not be read until the name is updated.
+func (s *ScopeNode) DefineUninit(name string, declared types.Type, n Node) error {
+	err := s.Define(name, declared, n)
+	if err != nil {
+		return err
+	}
+	s.uninit[len(s.uninit)-1] = true
+	return nil
+}
+
+// Initialized returns false if the name may not have been assigned on some path to the current control.
+func (s *ScopeNode) Initialized(name string) bool {
+	i, ok := s.lookup(name)
+	return !ok || !s.uninit[i]
+}
+
+// DefineFinal defines a name that cannot be updated.
+func (s *ScopeNode) DefineFinal(name string, declared types.Type, n Node) error {
+	err := s.Define(name, declared, n)
+	if err != nil {
+		return err
+	}
+	s.finals[len(s.finals)-1] = true
 	return nil
 }
 
+// Final returns true if the name was defined with DefineFinal.
+func (s *ScopeNode) Final(name string) bool {
+	i, ok := s.lookup(name)
+	return ok && s.finals[i]
+}
+
+// DeclaredType returns the type the name was defined with.
+func (s *ScopeNode) DeclaredType(name string) (types.Type, bool) {
+	i, ok := s.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return s.decls[i], true
+}
+
 func (s *ScopeNode) Lookup(name string) (Node, bool) {
 	i, ok := s.lookup(name)
 	if !ok {
@@ -77,13 +131,72 @@ func (s *ScopeNode) Update(name string, n Node) (bool, error) {
 	if !ok {
 		return false, nil
 	}
+	if s.finals[i] {
+		return true, errors.Errorf("Cannot update a final name: %s", name)
+	}
 	err := setIn(s, i, n)
 	if err != nil {
 		return true, err
 	}
+	s.uninit[i] = false
 	return true, nil
 }
 
+// Refine replaces the node of a name with a node of the same value with a more precise type. The value does not change, so final names can be refined.
+func (s *ScopeNode) Refine(name string, n Node) error {
+	i, ok := s.lookup(name)
+	if !ok {
+		return errors.Errorf("Cannot refine an unknown name: %s", name)
+	}
+	return setIn(s, i, n)
+}
+
+// Suggest returns the visible name closest to the unknown name by edit distance, if one is close enough to be a likely typo. Inner scopes are preferred over outer scopes.
+func (s *ScopeNode) Suggest(name string) (string, bool) {
+	// Short names are too similar to each other for suggestions to be useful
+	limit := (utf8.RuneCountInString(name) + 1) / 3
+	best, bestDistance := "", limit+1
+	for i := len(s.Scopes) - 1; i >= 0; i-- {
+		names := make([]string, 0, len(s.Scopes[i]))
+		for n := range s.Scopes[i] {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		for _, candidate := range names {
+			// Control and memory are not names of the source
+			if strings.HasPrefix(candidate, "$") {
+				continue
+			}
+			if d := editDistance(name, candidate); d < bestDistance {
+				best, bestDistance = candidate, d
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
 func (s *ScopeNode) lookup(name string) (int, bool) {
 	for i := len(s.Scopes) - 1; i >= 0; i-- {
 		if n, ok := s.Scopes[i][name]; ok {
@@ -93,6 +206,159 @@ func (s *ScopeNode) lookup(name string) (int, bool) {
 	return 0, false
 }
 
+// Dup creates a copy of the scope that points to the same nodes. Used to generate the different branches of control flow.
+func (s *ScopeNode) Dup() *ScopeNode {
+	dup := NewScopeNode()
+	for _, table := range s.Scopes {
+		dup.Scopes = append(dup.Scopes, maps.Clone(table))
+	}
+	for _, in := range Ins(s) {
+		addIn(dup, in)
+	}
+	dup.decls = slices.Clone(s.decls)
+	dup.finals = slices.Clone(s.finals)
+	dup.uninit = slices.Clone(s.uninit)
+	return dup
+}
+
+// Merge merges that scope into this scope, creating a region for the control and phis for every name that differs between the scopes. That scope is killed. Returns the new control.
+func (s *ScopeNode) Merge(that *ScopeNode) (Node, error) {
+	// A branch without control (returned or never taken) does not take part in the merge
+	if that.Control() == nil {
+		return s.Control(), kill(that)
+	}
+	if s.Control() == nil {
+		for i, in := range Ins(that) {
+			err := setIn(s, i, in)
+			if err != nil {
+				return nil, err
+			}
+		}
+		copy(s.uninit, that.uninit)
+		return s.Control(), kill(that)
+	}
+
+	r := NewRegionNode(s.Control(), that.Control())
+	err := s.SetControl(r)
+	if err != nil {
+		return nil, err
+	}
+
+	names := s.reverseNames()
+	for i := 1; i < NumOfIns(s); i++ {
+		// A name is only initialized after the merge if it is initialized on both paths
+		s.uninit[i] = s.uninit[i] || that.uninit[i]
+		if In(s, i) == In(that, i) {
+			continue
+		}
+		phi, err := peephole(NewPhiNode(names[i], s.decls[i], r, In(s, i), In(that, i)))
+		if err != nil {
+			return nil, err
+		}
+		err = setIn(s, i, phi)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = kill(that)
+	if err != nil {
+		return nil, err
+	}
+	control, err := peephole(r)
+	if err != nil {
+		return nil, err
+	}
+	return control, s.SetControl(control)
+}
+
+// DupLoop creates a copy of the scope for the body of a loop whose head is the current control. Every name gets a phi at the loop head, shared by both scopes. The back edges of the phis are set by EndLoop.
+func (s *ScopeNode) DupLoop() (*ScopeNode, error) {
+	names := s.reverseNames()
+	for i := 1; i < NumOfIns(s); i++ {
+		phi, err := peephole(NewPhiNode(names[i], s.decls[i], s.Control(), In(s, i), nil))
+		if err != nil {
+			return nil, err
+		}
+		err = setIn(s, i, phi)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.Dup(), nil
+}
+
+// EndLoop finishes a loop that was started with DupLoop on this scope. back is the scope that flows back to the loop head, and is killed.
+func (s *ScopeNode) EndLoop(back *ScopeNode) error {
+	loop := s.Control().(*LoopNode)
+	err := setIn(loop, 2, back.Control())
+	if err != nil {
+		return err
+	}
+	for i := 1; i < NumOfIns(s); i++ {
+		// Values from a dead back edge never reach the loop head
+		var value Node
+		if back.Control() != nil {
+			value = In(back, i)
+		}
+		err = setIn(In(s, i), 2, value)
+		if err != nil {
+			return err
+		}
+	}
+	loop.inProgress = false
+	err = kill(back)
+	if err != nil {
+		return err
+	}
+
+	// Now that the back edges are known, remove useless phis
+	for i := 1; i < NumOfIns(s); i++ {
+		phi, ok := In(s, i).(*PhiNode)
+		if !ok {
+			continue
+		}
+		n, err := peephole(phi)
+		if err != nil {
+			return err
+		}
+		if n != Node(phi) {
+			err = subsume(phi, n)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// A loop without a back edge is not a loop
+	if loop.Back() == nil {
+		return subsume(loop, loop.Entry())
+	}
+	return nil
+}
+
+// reverseNames returns the names of all inputs, indexed by input.
+func (s *ScopeNode) reverseNames() []string {
+	names := make([]string, NumOfIns(s))
+	for _, table := range s.Scopes {
+		for name, i := range table {
+			names[i] = name
+		}
+	}
+	return names
+}
+
+// PopTo pops scopes until there are depth scopes left.
+func (s *ScopeNode) PopTo(depth int) error {
+	for len(s.Scopes) > depth {
+		err := s.Pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *ScopeNode) Push() { s.Scopes = append(s.Scopes, symbolTable{}) }
 func (s *ScopeNode) Pop() error {
 	last := s.Scopes[len(s.Scopes)-1]
@@ -103,5 +369,8 @@ func (s *ScopeNode) Pop() error {
 		}
 	}
 	s.Scopes = s.Scopes[:len(s.Scopes)-1]
+	s.decls = s.decls[:NumOfIns(s)]
+	s.finals = s.finals[:NumOfIns(s)]
+	s.uninit = s.uninit[:NumOfIns(s)]
 	return nil
 }
@@ -0,0 +1,48 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// ShlNode shifts the lhs left. Only the lowest 6 bits of the rhs are used, as in Java.
+type ShlNode struct {
+	binaryNode
+}
+
+func NewShlNode(lhs Node, rhs Node) *ShlNode {
+	return initBinaryNode(&ShlNode{}, lhs, rhs)
+}
+
+func (s *ShlNode) GraphicLabel() string { return "<<" }
+func (s *ShlNode) label() string        { return "Shl" }
+
+func (s *ShlNode) compute() (types.Type, error) {
+	// 0<<x=>0
+	if isIntConstant(s.Lhs(), 0) {
+		return types.NewInt(0), nil
+	}
+	lhs, lOk := intConstant(s.Lhs())
+	rhs, rOk := shiftAmount(s.Rhs())
+	if !lOk || !rOk {
+		return types.Bottom, nil
+	}
+	return types.NewInt(lhs << rhs), nil
+}
+
+func (s *ShlNode) idealize() (Node, error) {
+	// x<<0=>x
+	if rhs, ok := shiftAmount(s.Rhs()); ok && rhs == 0 {
+		return s.Lhs(), nil
+	}
+	return nil, nil
+}
+
+func (s *ShlNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(s.Lhs(), sb)
+	sb.WriteString("<<")
+	toString(s.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -20,6 +20,12 @@ func newStartNode(args *types.Tuple) *startNode {
 	return s
 }
 
+// addArgs appends to the arguments of the start node, used for the initial memory once all structs are declared.
+func (s *startNode) addArgs(args ...types.Type) {
+	s.args = types.NewTuple(append(s.args.Types, args...)...)
+	s.typ = s.args
+}
+
 func (s *startNode) IsControl() bool      { return true }
 func (s *startNode) GraphicLabel() string { return "Start" }
 
@@ -0,0 +1,48 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// StoreNode stores a value in a field. Its inputs are the control, the memory of the alias class of the field, the struct reference, the value and, for array elements, the index. A store to array elements without an index writes all the elements. Its result is the new memory of the alias class.
+type StoreNode struct {
+	baseNode
+	field *types.Field
+}
+
+func NewStoreNode(control Node, field *types.Field, mem Node, ptr Node, value Node) *StoreNode {
+	return initBaseNode(&StoreNode{field: field}, control, mem, ptr, value)
+}
+
+// NewArrayStoreNode creates a store of the array element at the index.
+func NewArrayStoreNode(control Node, field *types.Field, mem Node, ptr Node, index Node, value Node) *StoreNode {
+	return initBaseNode(&StoreNode{field: field}, control, mem, ptr, value, index)
+}
+
+func (s *StoreNode) Mem() Node   { return In(s, 1) }
+func (s *StoreNode) Ptr() Node   { return In(s, 2) }
+func (s *StoreNode) Value() Node { return In(s, 3) }
+
+// Index returns the index of the stored array element, or nil for struct fields and stores to all elements.
+func (s *StoreNode) Index() Node {
+	if NumOfIns(s) < 5 {
+		return nil
+	}
+	return In(s, 4)
+}
+
+func (s *StoreNode) IsControl() bool      { return false }
+func (s *StoreNode) GraphicLabel() string { return "." + s.field.Name + "=" }
+func (s *StoreNode) label() string        { return "Store_" + s.field.Name }
+
+func (s *StoreNode) compute() (types.Type, error) { return types.NewMem(s.field.Alias), nil }
+func (s *StoreNode) idealize() (Node, error)      { return nil, nil }
+
+func (s *StoreNode) toStringInternal(sb *strings.Builder) {
+	toString(s.Ptr(), sb)
+	toStringField(s.field, s.Index(), sb)
+	sb.WriteString("=")
+	toString(s.Value(), sb)
+}
@@ -36,11 +36,11 @@ func (s *SubNode) compute() (types.Type, error) {
 
 func (s *SubNode) idealize() (Node, error) {
 	// 0 - x => -x
-	if lType, ok := Type(s.Lhs()).(*types.Int); ok && lType.Value == 0 {
+	if lType, ok := Type(s.Lhs()).(*types.Int); ok && lType.Constant() && lType.Value == 0 {
 		return NewMinusNode(s.Rhs()), nil
 	}
 	// x - 0 => x
-	if rType, ok := Type(s.Rhs()).(*types.Int); ok && rType.Value == 0 {
+	if rType, ok := Type(s.Rhs()).(*types.Int); ok && rType.Constant() && rType.Value == 0 {
 		return s.Lhs(), nil
 	}
 
@@ -0,0 +1,45 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+// SubFNode subtracts two floats.
+type SubFNode struct {
+	binaryNode
+}
+
+func NewSubFNode(lhs Node, rhs Node) *SubFNode {
+	return initBinaryNode(&SubFNode{}, lhs, rhs)
+}
+
+func (s *SubFNode) GraphicLabel() string { return "-" }
+func (s *SubFNode) label() string        { return "SubF" }
+
+func (s *SubFNode) compute() (types.Type, error) {
+	lhs, lOk := fltConstant(s.Lhs())
+	rhs, rOk := fltConstant(s.Rhs())
+	if lOk && rOk {
+		return types.NewFlt(lhs - rhs), nil
+	}
+	return types.FltBottom, nil
+}
+
+// idealize does not fold x-x to 0.0, since it is NaN for infinities and NaN.
+func (s *SubFNode) idealize() (Node, error) {
+	// x - 0.0 => x
+	if isFltConstant(s.Rhs(), 0) {
+		return s.Lhs(), nil
+	}
+	return nil, nil
+}
+
+func (s *SubFNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(s.Lhs(), sb)
+	sb.WriteString("-")
+	toString(s.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"strings"
+)
+
+var BoolTop = &Bool{Value: false, con: false}
+var BoolBottom = &Bool{Value: true, con: false}
+var True = &Bool{Value: true, con: true}
+var False = &Bool{Value: false, con: true}
+
+type Bool struct {
+	Value bool
+	con   bool
+}
+
+func NewBool(value bool) Type {
+	if value {
+		return True
+	}
+	return False
+}
+
+func (b *Bool) Simple() bool   { return false }
+func (b *Bool) Constant() bool { return b.con }
+func (b *Bool) ToString(sb *strings.Builder) {
+	switch b {
+	case BoolTop:
+		sb.WriteString("BoolTop")
+	case BoolBottom:
+		sb.WriteString("BoolBot")
+	case True:
+		sb.WriteString("true")
+	default:
+		sb.WriteString("false")
+	}
+}
+func (b *Bool) Meet(t Type) Type {
+	if b == t || t == Top {
+		return b
+	}
+	b0, ok := t.(*Bool)
+	if !ok {
+		return Bottom
+	}
+	if b.Bottom() || b0.Bottom() {
+		return BoolBottom
+	}
+	if b.Top() {
+		return b0
+	}
+	if b0.Top() {
+		return b
+	}
+	// Both are constants, and the constants are singletons
+	return BoolBottom
+}
+
+func (b *Bool) Top() bool    { return b == BoolTop }
+func (b *Bool) Bottom() bool { return b == BoolBottom }
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+var FltTop = &Flt{Value: 0, con: false}
+var FltBottom = &Flt{Value: 1, con: false}
+
+// Flt is a 64 bit IEEE-754 floating point number.
+type Flt struct {
+	Value float64
+	con   bool
+}
+
+func NewFlt(value float64) Type {
+	return &Flt{Value: value, con: true}
+}
+
+func (f *Flt) Simple() bool   { return false }
+func (f *Flt) Constant() bool { return f.con }
+func (f *Flt) ToString(sb *strings.Builder) {
+	switch {
+	case f.Top():
+		sb.WriteString("FltTop")
+	case f.Bottom():
+		sb.WriteString("FltBot")
+	default:
+		s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+		sb.WriteString(s)
+		// Print whole floats so they are not mistaken for ints
+		if !strings.ContainsAny(s, ".eIN") {
+			sb.WriteString(".0")
+		}
+	}
+}
+func (f *Flt) Meet(t Type) Type {
+	if f == t || t == Top {
+		return f
+	}
+	f0, ok := t.(*Flt)
+	if !ok {
+		return Bottom
+	}
+	if f.Bottom() || f0.Bottom() {
+		return FltBottom
+	}
+	if f.Top() {
+		return f0
+	}
+	if f0.Top() {
+		return f
+	}
+	// Compare the bits, so NaN is equal to itself and 0.0 is different from -0.0
+	if math.Float64bits(f.Value) == math.Float64bits(f0.Value) {
+		return f
+	}
+	return FltBottom
+}
+
+func (f *Flt) Top() bool    { return f == FltTop }
+func (f *Flt) Bottom() bool { return f == FltBottom }
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"math"
 	"strconv"
 	"strings"
 )
@@ -8,20 +9,47 @@ import (
 var IntTop = &Int{Value: 0, con: false}
 var IntBottom = &Int{Value: 1, con: false}
 
+// The sized ints are the values that fit in the width of a sized int type. Ints are 64 bits wide, so i64 is IntBottom.
+var I8 = &Int{Value: 1, bits: 8}
+var I16 = &Int{Value: 1, bits: 16}
+var I32 = &Int{Value: 1, bits: 32}
+var U8 = &Int{Value: 1, bits: 8, unsigned: true}
+var U16 = &Int{Value: 1, bits: 16, unsigned: true}
+var U32 = &Int{Value: 1, bits: 32, unsigned: true}
+
+// sizedInts are the sized ints ordered by the number of values they hold, so the first one containing a range is the smallest.
+var sizedInts = []*Int{I8, U8, I16, U16, I32, U32}
+
+// Int is a 64 bit integer. Non-constant ints either hold any value, or only the values of a sized int type.
 type Int struct {
 	Value int
 	con   bool
+
+	// bits is the width of a sized int, 0 for constants, IntTop and IntBottom
+	bits     int
+	unsigned bool
 }
 
 func NewInt(value int) Type {
 	return &Int{Value: value, con: true}
 }
 
-func (i *Int) Simple() bool                 { return false }
-func (i *Int) Constant() bool               { return true }
-func (i *Int) ToString(sb *strings.Builder) { sb.WriteString(strconv.Itoa(i.Value)) }
+func (i *Int) Simple() bool   { return false }
+func (i *Int) Constant() bool { return i.con }
+func (i *Int) ToString(sb *strings.Builder) {
+	switch {
+	case i.Top():
+		sb.WriteString("IntTop")
+	case i.Bottom():
+		sb.WriteString("IntBot")
+	case i.Sized():
+		sb.WriteString(i.Name())
+	default:
+		sb.WriteString(strconv.Itoa(i.Value))
+	}
+}
 func (i *Int) Meet(t Type) Type {
-	if i == t {
+	if i == t || t == Top {
 		return i
 	}
 	i0, ok := t.(*Int)
@@ -37,11 +65,89 @@ func (i *Int) Meet(t Type) Type {
 	if i0.Top() {
 		return i
 	}
-	if i.Value == i0.Value {
+	if i.con && i0.con && i.Value == i0.Value {
 		return i
 	}
-	return IntBottom
+	return FitInt(min(i.Min(), i0.Min()), max(i.Max(), i0.Max()))
 }
 
 func (i *Int) Top() bool    { return i == IntTop }
 func (i *Int) Bottom() bool { return i == IntBottom }
+
+// Sized returns true for the sized ints I8 to U32.
+func (i *Int) Sized() bool { return i.bits != 0 }
+
+// Bits returns the width of a sized int, and 64 for any other int.
+func (i *Int) Bits() int {
+	if !i.Sized() {
+		return 64
+	}
+	return i.bits
+}
+
+// Unsigned returns true for the unsigned sized ints.
+func (i *Int) Unsigned() bool { return i.unsigned }
+
+// Name returns the name of the type of a sized int, like i8 or u32, and int for any other int.
+func (i *Int) Name() string {
+	switch {
+	case !i.Sized():
+		return "int"
+	case i.unsigned:
+		return "u" + strconv.Itoa(i.bits)
+	}
+	return "i" + strconv.Itoa(i.bits)
+}
+
+// Min returns the smallest value of the int.
+func (i *Int) Min() int {
+	switch {
+	case i.con:
+		return i.Value
+	case i.unsigned:
+		return 0
+	case i.Sized():
+		return -1 << (i.bits - 1)
+	}
+	return math.MinInt64
+}
+
+// Max returns the largest value of the int.
+func (i *Int) Max() int {
+	switch {
+	case i.con:
+		return i.Value
+	case i.unsigned:
+		return 1<<i.bits - 1
+	case i.Sized():
+		return 1<<(i.bits-1) - 1
+	}
+	return math.MaxInt64
+}
+
+// Contains returns true if every value of t is a value of i.
+func (i *Int) Contains(t *Int) bool {
+	return t.Top() || (i.Min() <= t.Min() && t.Max() <= i.Max())
+}
+
+// Wrap returns v truncated to the width of a sized int, then sign or zero extended back to 64 bits. Any other int returns v unchanged.
+func (i *Int) Wrap(v int) int {
+	switch {
+	case !i.Sized():
+		return v
+	case i.unsigned:
+		return v & (1<<i.bits - 1)
+	}
+	shift := 64 - i.bits
+	return int(int64(v) << shift >> shift)
+}
+
+// FitInt returns the smallest sized int holding all the values from lo to hi, or IntBottom if there is none.
+func FitInt(lo int, hi int) *Int {
+	for _, s := range sizedInts {
+		if s.Min() <= lo && hi <= s.Max() {
+			return s
+		}
+	}
+	return IntBottom
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Mem is the state of the memory of a single alias class.
+type Mem struct {
+	Alias int
+}
+
+func NewMem(alias int) *Mem {
+	return &Mem{Alias: alias}
+}
+
+func (m *Mem) Simple() bool   { return false }
+func (m *Mem) Constant() bool { return false }
+
+func (m *Mem) ToString(sb *strings.Builder) {
+	sb.WriteString("Mem#")
+	sb.WriteString(strconv.Itoa(m.Alias))
+}
+
+func (m *Mem) Meet(t Type) Type {
+	if m == t || t == Top {
+		return m
+	}
+	if m0, ok := t.(*Mem); ok && m0.Alias == m.Alias {
+		return m
+	}
+	return Bottom
+}
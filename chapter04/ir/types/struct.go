@@ -0,0 +1,195 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Struct is the layout of a declared struct. Every field has its own alias class, so memory of different fields never overlaps.
+type Struct struct {
+	Name   string
+	Fields []*Field
+
+	// ptrs are the references to the struct, indexed by ptrIndex
+	ptrs [4]*MemPtr
+	// consts are the constant arrays of the struct by their bytes
+	consts map[string]*MemPtr
+}
+
+type Field struct {
+	Name  string
+	Type  Type
+	Alias int
+}
+
+func NewStruct(name string, fields []*Field) *Struct {
+	s := &Struct{Name: name, Fields: fields}
+	for i := range s.ptrs {
+		s.ptrs[i] = &MemPtr{Struct: s, Nullable: i&1 != 0, dual: i&2 != 0}
+	}
+	return s
+}
+
+// The fields of an array. Arrays are structs with a length and the elements, where the elements are accessed with an index.
+const (
+	ArrayLength = "#"
+	ArrayElems  = "[]"
+)
+
+// NewArray creates the layout of an array of elem, with the given alias classes for the length and the elements.
+func NewArray(elem *Int, lengthAlias int, elemsAlias int) *Struct {
+	return NewStruct(elem.Name()+"[]", []*Field{
+		{Name: ArrayLength, Type: IntBottom, Alias: lengthAlias},
+		{Name: ArrayElems, Type: elem, Alias: elemsAlias},
+	})
+}
+
+// IsArray returns true if the struct is the layout of an array.
+func (s *Struct) IsArray() bool { return s.Field(ArrayElems) != nil }
+
+// Field returns the field with the given name, or nil if there is no such field.
+func (s *Struct) Field(name string) *Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Ptr returns the type of a non-null reference to the struct.
+func (s *Struct) Ptr() *MemPtr { return s.ptrs[0] }
+
+// Const returns the type of a reference to a constant array of the struct holding the bytes of value, like a string literal. Constant arrays are unique by value, so equal values are the same reference.
+func (s *Struct) Const(value string) *MemPtr {
+	if c, ok := s.consts[value]; ok {
+		return c
+	}
+	if s.consts == nil {
+		s.consts = map[string]*MemPtr{}
+	}
+	c := &MemPtr{Struct: s, Value: value, con: true}
+	s.consts[value] = c
+	return c
+}
+
+// MemPtr is a reference to a struct, which is null if it is Nullable.
+//
+// References form a lattice by combining the struct and whether the reference may be null. Meeting two references meets their structs, and they may be null if either may be null. A struct meets itself, and meets a different struct to any struct. Dual references are above the non-null reference to the same struct, and are only used to compute joins. Constant arrays are just below the top reference, and any other meet forgets their value.
+type MemPtr struct {
+	// Struct is the referenced struct. It is nil when there is no struct, for null and the top reference, and for any struct when dual is set.
+	Struct *Struct
+	// Nullable is set when the reference may be null
+	Nullable bool
+	dual     bool
+	// Value is the bytes of a constant array
+	Value string
+	con   bool
+}
+
+// The references without a struct. Null is the only constant among them.
+var PtrTop = &MemPtr{}
+var Null = &MemPtr{Nullable: true}
+var PtrAny = &MemPtr{dual: true}
+var PtrBottom = &MemPtr{Nullable: true, dual: true}
+
+var noStructPtrs = [4]*MemPtr{PtrTop, Null, PtrAny, PtrBottom}
+
+// ptr returns the reference with the given properties. References are unique, so they can be compared with ==.
+func ptr(s *Struct, nullable bool, dual bool) *MemPtr {
+	i := 0
+	if nullable {
+		i |= 1
+	}
+	if dual {
+		i |= 2
+	}
+	if s == nil {
+		return noStructPtrs[i]
+	}
+	return s.ptrs[i]
+}
+
+func (m *MemPtr) Simple() bool   { return false }
+func (m *MemPtr) Constant() bool { return m == Null || m.con }
+
+func (m *MemPtr) ToString(sb *strings.Builder) {
+	switch m {
+	case PtrTop:
+		sb.WriteString("~*void")
+		return
+	case Null:
+		sb.WriteString("null")
+		return
+	}
+	if m.con {
+		sb.WriteString(strconv.Quote(m.Value))
+		return
+	}
+	if m.dual && m.Struct != nil {
+		sb.WriteString("~")
+	}
+	sb.WriteString("*")
+	if m.Struct == nil {
+		sb.WriteString("void")
+	} else {
+		sb.WriteString(m.Struct.Name)
+	}
+	if m.Nullable {
+		sb.WriteString("?")
+	}
+}
+
+func (m *MemPtr) Meet(t Type) Type {
+	if m == t || t == Top {
+		return m
+	}
+	m0, ok := t.(*MemPtr)
+	if !ok {
+		return Bottom
+	}
+	// A constant array is below the top reference, and meets anything else as a reference to its struct
+	switch {
+	case m0 == PtrTop:
+		return m
+	case m == PtrTop:
+		return m0
+	}
+	s, dual := m.meetStruct(m0)
+	return ptr(s, m.Nullable || m0.Nullable, dual)
+}
+
+// meetStruct returns the struct of the meet of two references.
+func (m *MemPtr) meetStruct(m0 *MemPtr) (*Struct, bool) {
+	switch {
+	case m.Struct == nil && !m.dual:
+		return m0.Struct, m0.dual
+	case m0.Struct == nil && !m0.dual:
+		return m.Struct, m.dual
+	case m.Struct == m0.Struct:
+		return m.Struct, m.dual && m0.dual
+	}
+	return nil, true
+}
+
+// Dual returns the dual of the reference, which flips both the struct and whether it may be null.
+func (m *MemPtr) Dual() *MemPtr { return ptr(m.Struct, !m.Nullable, !m.dual) }
+
+// Join returns the most general reference that is both m and m0.
+func (m *MemPtr) Join(m0 *MemPtr) *MemPtr {
+	return m.Dual().Meet(m0.Dual()).(*MemPtr).Dual()
+}
+
+// Isa returns true if every value of m is a value of m0.
+func (m *MemPtr) Isa(m0 *MemPtr) bool { return m.Meet(m0) == m0 }
+
+// OrNull returns the nullable reference to the same struct.
+func (m *MemPtr) OrNull() *MemPtr { return ptr(m.Struct, true, m.dual) }
+
+// NonNull returns the non-null reference to the same struct.
+func (m *MemPtr) NonNull() *MemPtr {
+	if !m.Nullable {
+		return m
+	}
+	return ptr(m.Struct, false, m.dual)
+}
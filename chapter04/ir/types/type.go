@@ -19,7 +19,17 @@ type simple struct {
 func (s *simple) Simple() bool                 { return true }
 func (s *simple) Constant() bool               { return s.constant }
 func (s *simple) ToString(sb *strings.Builder) { sb.WriteString(s.s) }
-func (s *simple) Meet(_ Type) Type             { return Bottom }
+func (s *simple) Meet(t Type) Type {
+	switch {
+	case s == t:
+		return s
+	case s == Top:
+		return t
+	case t == Top:
+		return s
+	}
+	return Bottom
+}
 
 var Top = &simple{s: "Top", constant: true}
 var Bottom = &simple{s: "Bottom", constant: false}
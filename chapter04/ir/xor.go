@@ -0,0 +1,62 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
+)
+
+type XorNode struct {
+	binaryNode
+}
+
+func NewXorNode(lhs Node, rhs Node) *XorNode {
+	return initBinaryNode(&XorNode{}, lhs, rhs)
+}
+
+func (x *XorNode) GraphicLabel() string { return "^" }
+func (x *XorNode) label() string        { return "Xor" }
+
+func (x *XorNode) compute() (types.Type, error) {
+	// x^x=>0
+	if x.Lhs() == x.Rhs() {
+		return types.NewInt(0), nil
+	}
+	lhs, lOk := intConstant(x.Lhs())
+	rhs, rOk := intConstant(x.Rhs())
+	if !lOk || !rOk {
+		return types.Bottom, nil
+	}
+	return types.NewInt(lhs ^ rhs), nil
+}
+
+func (x *XorNode) idealize() (Node, error) {
+	// x^0=>x
+	if isIntConstant(x.Rhs(), 0) {
+		return x.Lhs(), nil
+	}
+
+	if Type(x.Lhs()).Constant() && !Type(x.Rhs()).Constant() {
+		x.swap()
+		return x, nil
+	}
+
+	// (x^c1)^c2=>x^(c1^c2)
+	if l, ok := x.Lhs().(*XorNode); ok && Type(l.Rhs()).Constant() && Type(x.Rhs()).Constant() {
+		rhs, err := peephole(NewXorNode(l.Rhs(), x.Rhs()))
+		if err != nil {
+			return nil, err
+		}
+		return NewXorNode(l.Lhs(), rhs), nil
+	}
+
+	return nil, nil
+}
+
+func (x *XorNode) toStringInternal(sb *strings.Builder) {
+	sb.WriteString("(")
+	toString(x.Lhs(), sb)
+	sb.WriteString("^")
+	toString(x.Rhs(), sb)
+	sb.WriteString(")")
+}
@@ -0,0 +1,154 @@
+package simple
+
+import (
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ir"
+	"github.com/SeaOfNodes/Simple-Go/chapter04/parser"
+	"github.com/pkg/errors"
+)
+
+// ModuleExt is the extension of the files of modules.
+const ModuleExt = ".smp"
+
+// Resolver finds the source of imported modules.
+type Resolver interface {
+	// Resolve returns the name of the file of the module with the import path, which is shown in errors, and its source.
+	Resolve(path string) (name string, source string, err error)
+}
+
+// DirResolver resolves import paths to files in Dir, so import "geo/vec" loads geo/vec.smp in Dir.
+type DirResolver struct {
+	Dir string
+}
+
+func (d DirResolver) Resolve(path string) (string, string, error) {
+	name := filepath.Join(d.Dir, filepath.FromSlash(path)+ModuleExt)
+	source, err := os.ReadFile(name)
+	if err != nil {
+		return "", "", err
+	}
+	return name, string(source), nil
+}
+
+// SimpleFile compiles the source of the named file as the main module of a program. Imported modules are found by the resolver, and errors name the file they are in. Without a resolver, imports are errors.
+func SimpleFile(name string, source string, resolver Resolver, arg any) (*ir.ReturnNode, *ir.Generator, error) {
+	l := &loader{fset: token.NewFileSet(), resolver: resolver, modules: map[string]*ir.Module{}, sources: map[*token.File]string{}}
+	main, err := l.parse("", name, source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generator := ir.NewGenerator(getArgType(arg))
+	ret, err := generator.GenerateModule(main)
+	if err != nil {
+		// Enrich ast errors with the source info of their file
+		if a, ok := err.(*ir.ASTError); ok {
+			return nil, nil, l.sourceError(a, a.Pos)
+		}
+		return nil, nil, err
+	}
+	return ret, generator, nil
+}
+
+// loader parses a main module and the modules it imports. The files share a file set, so a position identifies the file it is in.
+type loader struct {
+	fset     *token.FileSet
+	resolver Resolver
+	// modules are the loaded modules by import path
+	modules map[string]*ir.Module
+	// sources are the sources of the parsed files
+	sources map[*token.File]string
+	// loading are the import paths of the modules being loaded, from the outermost import to the innermost one
+	loading []string
+}
+
+// parse parses the source of a module, and loads the modules it imports.
+func (l *loader) parse(path string, name string, source string) (*ir.Module, error) {
+	p := parser.NewFileParser(l.fset, name, source)
+	l.sources[p.File()] = source
+	body, err := p.Parse()
+	if err != nil {
+		return nil, l.syntaxErrors(err, p.File())
+	}
+
+	m := &ir.Module{Path: path, Body: body, Imports: map[string]*ir.Module{}}
+	for _, stmt := range body.List {
+		imp, ok := stmt.(*ast.ImportDecl)
+		if !ok {
+			continue
+		}
+		dep, err := l.load(imp)
+		if err != nil {
+			return nil, err
+		}
+		m.Imports[dep.Path] = dep
+	}
+	return m, nil
+}
+
+// load returns the module of an import declaration. Every module is loaded once, however many modules import it.
+func (l *loader) load(imp *ast.ImportDecl) (*ir.Module, error) {
+	path, err := strconv.Unquote(imp.Path.Value)
+	if err != nil || path == "." || !fs.ValidPath(path) {
+		return nil, l.importError(imp, "invalid import path %s", imp.Path.Value)
+	}
+	if i := slices.Index(l.loading, path); i >= 0 {
+		cycle := append(slices.Clone(l.loading[i:]), path)
+		return nil, l.importError(imp, "import cycle: %s", strings.Join(cycle, " -> "))
+	}
+	if m, ok := l.modules[path]; ok {
+		return m, nil
+	}
+	if l.resolver == nil {
+		return nil, l.importError(imp, "cannot import %s without a module resolver", imp.Path.Value)
+	}
+	name, source, err := l.resolver.Resolve(path)
+	if err != nil {
+		return nil, l.importError(imp, "cannot import %s: %v", imp.Path.Value, err)
+	}
+
+	l.loading = append(l.loading, path)
+	m, err := l.parse(path, name, source)
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		return nil, err
+	}
+	l.modules[path] = m
+	return m, nil
+}
+
+func (l *loader) importError(imp *ast.ImportDecl, msgFormat string, args ...any) error {
+	return l.sourceError(errors.Errorf("Import error: "+msgFormat, args...), imp.Path.Pos())
+}
+
+// sourceError returns the error at the position with the source info of its file.
+func (l *loader) sourceError(err error, pos token.Pos) error {
+	file := l.fset.File(pos)
+	if file == nil {
+		return err
+	}
+	return &SourceError{err, l.sources[file], file.Offset(pos), file}
+}
+
+// syntaxErrors enriches the syntax errors of the parser with the source info of the file.
+func (l *loader) syntaxErrors(err error, file *token.File) error {
+	switch e := err.(type) {
+	case *parser.SyntaxError:
+		return &SourceError{e, l.sources[file], e.Offset, file}
+	case parser.SyntaxErrors:
+		errs := make(SourceErrors, len(e))
+		for i, s := range e {
+			errs[i] = &SourceError{s, l.sources[file], s.Offset, file}
+		}
+		return errs
+	}
+	return err
+}
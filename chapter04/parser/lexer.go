@@ -4,6 +4,7 @@ import (
 	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -14,6 +15,16 @@ var NANError = errors.New("not a number")
 type lexer struct {
 	input    []byte
 	position int
+
+	// comments are all the comments skipped so far, in source order
+	comments []comment
+	// err is set when a block comment is not terminated. It is reported once parsing is done, since the rest of the input is part of the comment.
+	err *SyntaxError
+}
+
+type comment struct {
+	offset int
+	text   string
 }
 
 func (l *lexer) IsEOF() bool {
@@ -42,21 +53,72 @@ func (l *lexer) isWhitespace() bool {
 	return c <= ' '
 }
 
-// skipWhitespace forwards the current position until the next non-whitespace character
+// skipWhitespace forwards the current position until the next character that is not whitespace or part of a comment
 func (l *lexer) skipWhitespace() {
-	for l.isWhitespace() {
-		l.position++
+	for {
+		switch {
+		case l.isWhitespace():
+			l.position++
+		case l.peekAt(0) == '/' && l.peekAt(1) == '/':
+			l.skipLineComment()
+		case l.peekAt(0) == '/' && l.peekAt(1) == '*':
+			l.skipBlockComment()
+		default:
+			return
+		}
 	}
 }
 
-// isValidIDStart returns true when b can be the start of an identifier
-func isValidIDStart(b byte) bool {
-	return unicode.IsLetter(rune(b)) || b == '_'
+// skipLineComment skips a // comment up to the end of the line. The newline is not part of the comment.
+func (l *lexer) skipLineComment() {
+	start := l.position
+	end := slices.Index(l.input[start:], '\n')
+	if end < 0 {
+		l.position = len(l.input)
+	} else {
+		l.position = start + end
+	}
+	l.addComment(start)
 }
 
-// isValidIDByte returns true when b is a valid identifier character
-func isValidIDByte(b byte) bool {
-	return unicode.In(rune(b), unicode.Letter, unicode.Digit) || b == '_'
+// skipBlockComment skips a /* */ comment. An unterminated comment runs until the end of input.
+func (l *lexer) skipBlockComment() {
+	start := l.position
+	end := strings.Index(string(l.input[start+2:]), "*/")
+	if end < 0 {
+		l.position = len(l.input)
+		if l.err == nil {
+			l.err = syntaxError(start, "comment not terminated")
+		}
+		return
+	}
+	l.position = start + 2 + end + 2
+	l.addComment(start)
+}
+
+// addComment records the comment from start to the current position. Comments are skipped again when the lexer backtracks, so they are only recorded once.
+func (l *lexer) addComment(start int) {
+	if len(l.comments) > 0 && l.comments[len(l.comments)-1].offset >= start {
+		return
+	}
+	l.comments = append(l.comments, comment{offset: start, text: string(l.input[start:l.position])})
+}
+
+// peekRune decodes the UTF-8 rune at the current position. Returns utf8.RuneError with size 1 for invalid encodings, and size 0 at the end of input.
+func (l *lexer) peekRune() (rune, int) {
+	return utf8.DecodeRune(l.input[l.position:])
+}
+
+// Identifiers follow the rule of Go: a letter or an underscore, followed by letters, digits and underscores. Letters and digits are any Unicode letter (category L) and decimal digit (category Nd).
+
+// isValidIDStart returns true when r can be the start of an identifier
+func isValidIDStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// isValidIDRune returns true when r is a valid identifier character
+func isValidIDRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
 }
 
 func (l *lexer) parseToken(isInToken func(b byte) bool) (string, int) {
@@ -74,18 +136,106 @@ func (l *lexer) parseToken(isInToken func(b byte) bool) (string, int) {
 }
 
 func (l *lexer) parseID() (string, int) {
-	return l.parseToken(isValidIDByte)
+	start := l.position
+	for {
+		r, size := l.peekRune()
+		if size == 0 || !isValidIDRune(r) {
+			break
+		}
+		l.position += size
+	}
+	return string(l.input[start:l.position]), start
+}
+
+// peekAt returns the byte at offset from the current position, or 0 if it is past the end of input.
+func (l *lexer) peekAt(offset int) byte {
+	if l.position+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.position+offset]
 }
 
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isHexDigit(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// parseNumberString parses an integer or a float. Floats have a fraction (1.5), an exponent (2e10) or both. Integers may be hex (0x1F) or binary (0b101), and digits may be separated by underscores (1_000).
 func (l *lexer) parseNumberString() (string, int, error) {
-	s, pos := l.parseToken(func(b byte) bool { return unicode.IsDigit(rune(b)) })
+	if !isDigit(l.peekAt(0)) {
+		return "", l.position, NANError
+	}
+	if l.peekAt(0) == '0' {
+		switch l.peekAt(1) {
+		case 'x', 'X':
+			return l.parsePrefixedNumber(isHexDigit, "hexadecimal")
+		case 'b', 'B':
+			return l.parsePrefixedNumber(isDigit, "binary")
+		}
+	}
+
+	s, pos := l.parseToken(func(b byte) bool { return isDigit(b) || b == '_' })
 	if len(s) > 1 && s[0] == '0' {
 		return "", pos, errors.New("integer values cannot start with '0'")
 	}
-	if len(s) == 0 {
-		return "", pos, NANError
+	if err := checkSeparators(s); err != nil {
+		return "", pos, err
+	}
+
+	// The fraction must start with a digit, so 1.x is not a float
+	if l.peekAt(0) == '.' && isDigit(l.peekAt(1)) {
+		l.position++
+		l.parseToken(isDigit)
+	}
+	if e := l.peekAt(0); e == 'e' || e == 'E' {
+		digit := 1
+		if sign := l.peekAt(1); sign == '+' || sign == '-' {
+			digit = 2
+		}
+		if isDigit(l.peekAt(digit)) {
+			l.position += digit
+			l.parseToken(isDigit)
+		}
+	}
+	return string(l.input[pos:l.position]), pos, nil
+}
+
+// parsePrefixedNumber parses an integer with a 0x or 0b prefix. Binary digits are parsed as decimal digits, so that 0b12 is reported as an invalid digit rather than as 0b1 followed by 2.
+func (l *lexer) parsePrefixedNumber(isInBase func(b byte) bool, base string) (string, int, error) {
+	start := l.position
+	l.position += 2
+	digits, pos := l.parseToken(func(b byte) bool { return isInBase(b) || b == '_' })
+	if strings.Trim(digits, "_") == "" {
+		return "", start, errors.Errorf("%s literal has no digits", base)
+	}
+	if base == "binary" {
+		if i := strings.IndexFunc(digits, func(r rune) bool { return r != '0' && r != '1' && r != '_' }); i >= 0 {
+			return "", pos + i, errors.Errorf("invalid digit '%c' in binary literal", digits[i])
+		}
+	}
+	if err := checkSeparators(digits); err != nil {
+		return "", start, err
+	}
+	return string(l.input[start:l.position]), start, nil
+}
+
+// checkSeparators returns an error if underscores do not separate digits. An underscore may follow the prefix of hex and binary literals, as in Go.
+func checkSeparators(digits string) error {
+	if strings.Contains(digits, "__") || strings.HasSuffix(digits, "_") {
+		return errors.New("'_' must separate successive digits")
+	}
+	return nil
+}
+
+// isFloat returns true if the number returned by parseNumberString is a float.
+func isFloat(num string) bool {
+	if len(num) > 1 && num[0] == '0' && strings.ContainsRune("xXbB", rune(num[1])) {
+		return false
 	}
-	return s, pos, nil
+	return strings.ContainsAny(num, ".eE")
 }
 
 // ReadNumber skips whitespaces and retrieves the next number from input. If the next token is not a valid number, an error is returned.
@@ -94,25 +244,68 @@ func (l *lexer) ReadNumber() (string, int, error) {
 	return l.parseNumberString()
 }
 
+// ReadString skips whitespaces and reads a string literal if it is the next token. Returns the literal as written in the source, quotes included, the offset of the literal and true if one was read. The escape sequences are those of Go: \a \b \f \n \r \t \v \\ \" and \x followed by two hex digits.
+func (l *lexer) ReadString() (string, int, bool, error) {
+	l.skipWhitespace()
+	start := l.position
+	if l.peekAt(0) != '"' {
+		return "", start, false, nil
+	}
+	l.position++
+	for {
+		r, size := l.peekRune()
+		switch {
+		case size == 0 || r == '\n':
+			return "", start, true, syntaxError(start, "string literal not terminated")
+		case r == utf8.RuneError && size == 1:
+			return "", start, true, syntaxError(l.position, "invalid UTF-8 encoding")
+		case r == '\\':
+			if err := l.skipEscape(); err != nil {
+				return "", start, true, err
+			}
+			continue
+		}
+		l.position += size
+		if r == '"' {
+			return string(l.input[start:l.position]), start, true, nil
+		}
+	}
+}
+
+// skipEscape skips the escape sequence at the current position.
+func (l *lexer) skipEscape() error {
+	switch c := l.peekAt(1); {
+	case c != 0 && strings.IndexByte(`abfnrtv\"`, c) >= 0:
+		l.position += 2
+	case c == 'x' && isHexDigit(l.peekAt(2)) && isHexDigit(l.peekAt(3)):
+		l.position += 4
+	default:
+		return syntaxError(l.position, "invalid escape sequence")
+	}
+	return nil
+}
+
 // ReadToken skips whitespaces and retrieves the next token from input. Returns the token, the offset of the start of the token, true if the token is a valid identifier and an error if one occurred.
 func (l *lexer) ReadToken() (string, int, bool, error) {
 	l.skipWhitespace()
-	b, ok := l.peek()
+	r, size := l.peekRune()
 	switch {
-	case !ok:
+	case size == 0:
 		return "", l.position, false, nil
-	case isValidIDStart(b):
+	case r == utf8.RuneError && size == 1:
+		return "", l.position, false, syntaxError(l.position, "invalid UTF-8 encoding")
+	case isValidIDStart(r):
 		id, pos := l.parseID()
 		return id, pos, true, nil
-	case unicode.IsNumber(rune(b)):
+	case size == 1 && isDigit(byte(r)):
 		num, pos, err := l.parseNumberString()
 		return num, pos, false, err
 	}
-	l.position++
-	return string(b), l.position - 1, false, nil
+	l.position += size
+	return string(r), l.position - size, false, nil
 }
 
-var ops = []string{"+", "-", "*", "/", "=", "==", "<=", ">=", "<", ">", "!", "!="}
+var ops = []string{"+", "-", "*", "/", "=", "==", "<=", ">=", "<", ">", "!", "!=", "&&", "||", "&", "|", "^", "<<", ">>", ">>>"}
 
 func isOp(s string) bool {
 	return slices.Contains(ops, s)
@@ -152,26 +345,63 @@ func (l *lexer) ReadOp() (string, int, bool) {
 	}
 }
 
-// ReadByte retreives the next non-whitespace byte from input. Returns false if there are no non-whitespace bytes in input.
-func (l *lexer) ReadByte() (byte, int, bool) {
+var assignOpList = []string{"+=", "-=", "*=", "/=", "++", "--", "="}
+
+// ReadAssignOp skips whitespaces and retrieves the next assignment operator (= += -= *= /=) or increment (++ --) from input. == is not an assignment.
+func (l *lexer) ReadAssignOp() (string, int, bool) {
 	l.skipWhitespace()
-	b, ok := l.nextByte()
-	if !ok {
+	for _, op := range assignOpList {
+		if strings.HasPrefix(string(l.input[l.position:]), op) {
+			if op == "=" && l.peekAt(1) == '=' {
+				break
+			}
+			l.position += len(op)
+			return op, l.position - len(op), true
+		}
+	}
+	return "", l.position, false
+}
+
+// ReadNextRune retreives the next non-whitespace rune from input. Returns false if there are no non-whitespace runes in input.
+func (l *lexer) ReadNextRune() (rune, int, bool) {
+	l.skipWhitespace()
+	r, size := l.peekRune()
+	if size == 0 {
 		return 0, l.position, false
 	}
-	return b, l.position - 1, true
+	l.position += size
+	return r, l.position - size, true
 }
 
 func (l *lexer) ReadID() (string, int, bool) {
 	l.skipWhitespace()
-	b, ok := l.peek()
-	if !ok || !isValidIDStart(b) {
+	r, size := l.peekRune()
+	if size == 0 || !isValidIDStart(r) {
 		return "", l.position, false
 	}
 	id, pos := l.parseID()
 	return id, pos, true
 }
 
+// PeekID returns the next identifier without reading it. Returns false if the next token is not an identifier.
+func (l *lexer) PeekID() (string, bool) {
+	start := l.position
+	id, _, ok := l.ReadID()
+	l.position = start
+	return id, ok
+}
+
+// ReadKeyword reads the next identifier only if it is the given keyword. Returns the offset of the keyword and true if it was read.
+func (l *lexer) ReadKeyword(keyword string) (int, bool) {
+	start := l.position
+	id, pos, ok := l.ReadID()
+	if !ok || id != keyword {
+		l.position = start
+		return pos, false
+	}
+	return pos, true
+}
+
 func (l *lexer) Read(next byte) (int, bool) {
 	l.skipWhitespace()
 	b, ok := l.peek()
@@ -1,12 +1,10 @@
 package parser
 
 import (
-	"go/ast"
-	"go/printer"
 	"go/token"
 	"strings"
 
-	"github.com/SeaOfNodes/Simple-Go/chapter04/ir"
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/pkg/errors"
 )
 
@@ -20,31 +18,142 @@ func syntaxError(offset int, msgFormat string, args ...any) *SyntaxError {
 	return &SyntaxError{error: internal, Offset: offset}
 }
 
+// SyntaxErrors are all the syntax errors of a source, in source order. Parse returns it when there is more than one error.
+type SyntaxErrors []*SyntaxError
+
+func (s SyntaxErrors) Error() string {
+	msgs := make([]string, len(s))
+	for i, err := range s {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (s SyntaxErrors) Unwrap() []error {
+	errs := make([]error, len(s))
+	for i, err := range s {
+		errs[i] = err
+	}
+	return errs
+}
+
 type Parser struct {
 	lexer  lexer
 	file   *token.File
-	fset   *token.FileSet
 	source string
+
+	// loopDepth is the number of loops around the statement being parsed
+	loopDepth int
+	// switchDepth is the number of switches around the statement being parsed
+	switchDepth int
+	// depth is the number of blocks and branches around the statement being parsed
+	depth int
+
+	// errors are the syntax errors found so far. Parsing continues after an error from the next statement.
+	errors SyntaxErrors
 }
 
 func NewParser(source string) *Parser {
-	fset := token.NewFileSet()
-	return &Parser{source: source, lexer: lexer{input: []byte(source)}, fset: fset, file: fset.AddFile("", 1, len(source))}
+	return NewFileParser(token.NewFileSet(), "", source)
+}
+
+// NewFileParser creates a parser for the source of the named file. The file is added to fset, so the positions of files parsed with the same fset do not overlap.
+func NewFileParser(fset *token.FileSet, name string, source string) *Parser {
+	file := fset.AddFile(name, fset.Base(), len(source))
+	file.SetLinesForContent([]byte(source))
+	return &Parser{source: source, lexer: lexer{input: []byte(source)}, file: file}
 }
 
-func (p *Parser) Parse() (ast.Node, error) {
+// File returns the file of the source, which maps offsets and positions to lines.
+func (p *Parser) File() *token.File {
+	return p.file
+}
+
+func (p *Parser) Parse() (*ast.BlockStmt, error) {
 	n, err := p.parseBlock(p.offsetToPos(0), false)
+	// An unterminated comment swallows the rest of the input, so any other error is caused by it
+	if p.lexer.err != nil {
+		return nil, p.lexer.err
+	}
 	if err != nil {
 		return nil, err
 	}
-	if b, offset, ok := p.lexer.ReadByte(); ok {
-		return nil, syntaxError(offset, "unexpected %c", b)
+	if r, offset, ok := p.lexer.ReadNextRune(); ok {
+		p.errors = append(p.errors, syntaxError(offset, "unexpected %c", r))
+	}
+	switch len(p.errors) {
+	case 0:
+		return n, nil
+	case 1:
+		return nil, p.errors[0]
+	}
+	return nil, p.errors
+}
+
+// recover records a syntax error and skips to the start of the next statement, which is after the next ; or before the next } that closes a block. Blocks opened while skipping are skipped as a whole. Errors that are not syntax errors are returned.
+func (p *Parser) recover(err error) error {
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+	// Errors at the same offset are caused by the same mistake
+	if len(p.errors) == 0 || p.errors[len(p.errors)-1].Offset != syntaxErr.Offset {
+		p.errors = append(p.errors, syntaxErr)
+	}
+
+	depth := 0
+	for {
+		start := p.lexer.position
+		t, offset, _, err := p.lexer.ReadToken()
+		if err != nil {
+			// Skip the byte that could not be read
+			if p.lexer.position == start {
+				p.lexer.position++
+			}
+			continue
+		}
+		switch t {
+		case "":
+			return nil
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		case "{":
+			depth++
+		case "}":
+			if depth == 0 {
+				p.lexer.position = offset
+				return nil
+			}
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
 	}
-	return n, nil
+}
+
+// Comments returns the comments of the parsed source, with their positions. Comments on adjacent lines form a group.
+func (p *Parser) Comments() []*ast.CommentGroup {
+	var groups []*ast.CommentGroup
+	end := 0
+	for _, c := range p.lexer.comments {
+		between := p.source[end:c.offset]
+		if len(groups) == 0 || strings.TrimSpace(between) != "" || strings.Count(between, "\n") > 1 {
+			groups = append(groups, &ast.CommentGroup{})
+		}
+		group := groups[len(groups)-1]
+		group.List = append(group.List, &ast.Comment{Slash: p.offsetToPos(c.offset), Text: c.text})
+		end = c.offset + len(c.text)
+	}
+	return groups
 }
 
 func (p *Parser) blockEnd(block *ast.BlockStmt, endInCurly bool) bool {
 	if !endInCurly {
+		// Trailing whitespace and comments are not a statement
+		p.lexer.skipWhitespace()
 		return p.lexer.IsEOF()
 	}
 
@@ -57,11 +166,23 @@ func (p *Parser) blockEnd(block *ast.BlockStmt, endInCurly bool) bool {
 }
 
 func (p *Parser) parseBlock(pos token.Pos, endInCurly bool) (*ast.BlockStmt, error) {
+	if endInCurly {
+		p.depth++
+		defer func() { p.depth-- }()
+	}
 	block := &ast.BlockStmt{Lbrace: pos}
 	for !p.blockEnd(block, endInCurly) {
 		n, err := p.parseStatement()
 		if err != nil {
-			return nil, err
+			err = p.recover(err)
+			if err != nil {
+				return nil, err
+			}
+			// The block is not closed, which was reported when the end of input was read as a statement
+			if endInCurly && p.errors[len(p.errors)-1].Offset == len(p.lexer.input) {
+				return block, nil
+			}
+			continue
 		}
 		if n != nil {
 			block.List = append(block.List, n)
@@ -70,8 +191,6 @@ func (p *Parser) parseBlock(pos token.Pos, endInCurly bool) (*ast.BlockStmt, err
 	return block, nil
 }
 
-var showGraph ast.Stmt
-
 func (p *Parser) parseStatement() (ast.Stmt, error) {
 	t, offset, isID, err := p.lexer.ReadToken()
 	if err != nil {
@@ -86,13 +205,63 @@ func (p *Parser) parseStatement() (ast.Stmt, error) {
 		if err != nil {
 			return nil, err
 		}
-	case "int":
-		n, err = p.parseDecl(pos)
+	case "var":
+		n, err = p.parseInferredDecl(pos, false)
+		if err != nil {
+			return nil, err
+		}
+	case "val":
+		n, err = p.parseInferredDecl(pos, true)
+		if err != nil {
+			return nil, err
+		}
+	case "struct":
+		n, err = p.parseStruct(pos, offset)
+		if err != nil {
+			return nil, err
+		}
+	case "import":
+		n, err = p.parseImport(pos, offset)
+		if err != nil {
+			return nil, err
+		}
+	case "export":
+		n, err = p.parseExport(pos, offset)
+		if err != nil {
+			return nil, err
+		}
+	case "if":
+		n, err = p.parseIf(pos)
+		if err != nil {
+			return nil, err
+		}
+	case "while":
+		n, err = p.parseWhile(pos)
+		if err != nil {
+			return nil, err
+		}
+	case "for":
+		n, err = p.parseFor(pos)
+		if err != nil {
+			return nil, err
+		}
+	case "switch":
+		n, err = p.parseSwitch(pos)
+		if err != nil {
+			return nil, err
+		}
+	case "break":
+		n, err = p.parseLoopBranch(pos, offset, token.BREAK)
+		if err != nil {
+			return nil, err
+		}
+	case "continue":
+		n, err = p.parseLoopBranch(pos, offset, token.CONTINUE)
 		if err != nil {
 			return nil, err
 		}
 	case "#":
-		return p.parseInstruction()
+		return p.parseInstruction(pos)
 	case "{":
 		n, err = p.parseBlock(pos, true)
 		if err != nil {
@@ -102,8 +271,26 @@ func (p *Parser) parseStatement() (ast.Stmt, error) {
 		// Empty statement is allowed
 		return nil, nil
 	default:
+		if primitiveTypes[t] {
+			typ, err := p.parseArrayType(&ast.Ident{NamePos: pos, Name: t})
+			if err != nil {
+				return nil, err
+			}
+			return p.parseDecl(typ)
+		}
+		if keywords[t] {
+			return nil, syntaxError(offset, "unexpected keyword %s", t)
+		}
 		if isID {
-			return p.parseExprStatement(t, pos)
+			typ := &ast.Ident{NamePos: pos, Name: t}
+			// A name followed by another name declares a variable of a struct type, and a ? makes the struct type nullable
+			if nullable := p.parseNullable(typ); nullable != typ {
+				return p.parseDecl(nullable)
+			}
+			if _, ok := p.lexer.PeekID(); ok {
+				return p.parseDecl(typ)
+			}
+			return p.parseExprStatement(typ)
 		}
 		return nil, syntaxError(offset, "expected a statement got %s", t)
 	}
@@ -111,32 +298,57 @@ func (p *Parser) parseStatement() (ast.Stmt, error) {
 	return n, nil
 }
 
-func (p *Parser) parseInstruction() (ast.Stmt, error) {
+// instructions are the names of the compiler instructions.
+var instructions = map[string]bool{"showGraph": true, "disablePeephole": true}
+
+func (p *Parser) parseInstruction(pos token.Pos) (*ast.InstructionStmt, error) {
 	inst, offset, _, err := p.lexer.ReadToken()
 	if err != nil {
 		return nil, err
 	}
+	if !instructions[inst] {
+		return nil, syntaxError(offset, "unknown compiler instruction")
+	}
+	return &ast.InstructionStmt{Hash: pos, Name: &ast.Ident{NamePos: p.offsetToPos(offset), Name: inst}}, nil
+}
 
-	switch inst {
-	case "showGraph":
-		return ir.ShowGraphInst, nil
-	case "disablePeephole":
-		return ir.DisablePeepholeInst, nil
+func (p *Parser) parseExprStatement(id *ast.Ident) (ast.Stmt, error) {
+	n, err := p.parseAssignment(id)
+	if err != nil {
+		return nil, err
 	}
-	return nil, syntaxError(offset, "unknown compiler instruction")
+	return n, p.parseSemicolon()
 }
 
-func (p *Parser) parseExprStatement(name string, namePos token.Pos) (ast.Stmt, error) {
-	id := &ast.Ident{NamePos: namePos, Name: name}
-	offset, ok := p.lexer.Read('=')
+// parseAssignment parses an assignment, a compound assignment or an increment, starting from the name id and without the semicolon.
+func (p *Parser) parseAssignment(id *ast.Ident) (ast.Stmt, error) {
+	lhs, err := p.parsePostfix(id)
+	if err != nil {
+		return nil, err
+	}
+	op, offset, ok := p.lexer.ReadAssignOp()
 	if !ok {
 		return nil, syntaxError(offset, "expected assignment (=)")
 	}
+	if op == "++" || op == "--" {
+		return &ast.IncDecStmt{X: lhs, TokPos: p.offsetToPos(offset), Tok: assignOps[op]}, nil
+	}
 	expr, err := p.parseExpr()
 	if err != nil {
 		return nil, err
 	}
-	return &ast.AssignStmt{Lhs: []ast.Expr{id}, Tok: token.ASSIGN, TokPos: p.offsetToPos(offset), Rhs: []ast.Expr{expr}}, nil
+	return &ast.AssignStmt{Lhs: lhs, Tok: assignOps[op], TokPos: p.offsetToPos(offset), Rhs: expr}, nil
+}
+
+// assignOps maps the operators read by ReadAssignOp to their tokens.
+var assignOps = map[string]token.Token{
+	"=":  token.ASSIGN,
+	"+=": token.ADD_ASSIGN,
+	"-=": token.SUB_ASSIGN,
+	"*=": token.MUL_ASSIGN,
+	"/=": token.QUO_ASSIGN,
+	"++": token.INC,
+	"--": token.DEC,
 }
 
 func (p *Parser) parseSemicolon() error {
@@ -155,18 +367,81 @@ func (p *Parser) parseID() (*ast.Ident, error) {
 	return &ast.Ident{NamePos: p.offsetToPos(nameOffset), Name: name}, nil
 }
 
-func (p *Parser) parseDecl(pos token.Pos) (*ast.DeclStmt, error) {
-	name, err := p.parseID()
+// keywords are the reserved words of Simple, which cannot be used as names.
+var keywords = map[string]bool{
+	"bool": true, "break": true, "case": true, "continue": true, "default": true, "else": true, "export": true, "false": true,
+	"flt": true, "for": true, "i8": true, "i16": true, "i32": true, "i64": true, "if": true, "import": true, "int": true, "new": true,
+	"null": true, "return": true, "struct": true, "switch": true, "true": true, "u8": true, "u16": true, "u32": true, "val": true,
+	"var": true, "while": true,
+}
+
+// exprKeywords are the keywords that can start an expression: constants, allocations and conversions.
+var exprKeywords = map[string]bool{
+	"true": true, "false": true, "null": true, "new": true, "int": true, "flt": true,
+	"i8": true, "i16": true, "i32": true, "i64": true, "u8": true, "u16": true, "u32": true,
+}
+
+// primitiveTypes are the type names that are not structs.
+var primitiveTypes = map[string]bool{
+	"int": true, "flt": true, "bool": true,
+	"i8": true, "i16": true, "i32": true, "i64": true, "u8": true, "u16": true, "u32": true,
+}
+
+// parseName parses the name in a declaration, which cannot be a keyword.
+func (p *Parser) parseName() (*ast.Ident, error) {
+	id, err := p.parseID()
 	if err != nil {
 		return nil, err
 	}
+	if keywords[id.Name] {
+		return nil, syntaxError(p.PosToOffset(id.NamePos), "%s is a reserved keyword", id.Name)
+	}
+	return id, nil
+}
+
+// parseArrayType parses the [] following the element type elt, if there is one, and the ? of a nullable array.
+func (p *Parser) parseArrayType(elt *ast.Ident) (ast.TypeExpr, error) {
+	lOffset, ok := p.lexer.Read('[')
+	if !ok {
+		return p.parseNullable(elt), nil
+	}
+	if offset, ok := p.lexer.Read(']'); !ok {
+		return nil, syntaxError(offset, "expected ]")
+	}
+	return p.parseNullable(&ast.ArrayType{Lbrack: p.offsetToPos(lOffset), Elt: elt}), nil
+}
+
+// parseNullable parses the ? following a reference type, if there is one.
+func (p *Parser) parseNullable(typ ast.TypeExpr) ast.TypeExpr {
+	offset, ok := p.lexer.Read('?')
+	if !ok {
+		return typ
+	}
+	return &ast.NullableType{X: typ, Question: p.offsetToPos(offset)}
+}
+
+// parseDecl parses a declaration of a variable or a function, starting from the name after the type typ.
+func (p *Parser) parseDecl(typ ast.TypeExpr) (ast.Stmt, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.lexer.Read('('); ok {
+		return p.parseFunction(typ, name)
+	}
+
+	// A declaration without a value must be assigned before it is used
+	if _, ok := p.lexer.Read(';'); ok {
+		return &ast.VarDecl{Decl: typ.Pos(), Type: typ, Name: name}, nil
+	}
 
 	opOffset, ok := p.lexer.Read('=')
 	if !ok {
 		return nil, syntaxError(opOffset, "expected =")
 	}
 
-	value, err := p.parseBinary()
+	value, err := p.parseExpr()
 	if err != nil {
 		return nil, err
 	}
@@ -176,17 +451,381 @@ func (p *Parser) parseDecl(pos token.Pos) (*ast.DeclStmt, error) {
 		return nil, err
 	}
 
-	return &ast.DeclStmt{
-		Decl: &ast.GenDecl{
-			Specs: []ast.Spec{
-				&ast.ValueSpec{
-					Names:  []*ast.Ident{name},
-					Type:   &ast.Ident{Name: "int", NamePos: pos},
-					Values: []ast.Expr{value},
-				},
-			},
-		},
-	}, nil
+	return &ast.VarDecl{Decl: typ.Pos(), Type: typ, Name: name, Value: value}, nil
+}
+
+// parseInferredDecl parses a declaration whose type is inferred from its value: var x = 1; or val x = 1;. final is true for val, which cannot be assigned again.
+func (p *Parser) parseInferredDecl(pos token.Pos, final bool) (*ast.VarDecl, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	opOffset, ok := p.lexer.Read('=')
+	if !ok {
+		return nil, syntaxError(opOffset, "expected =")
+	}
+
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.parseSemicolon()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.VarDecl{Decl: pos, Name: name, Value: value, Final: final}, nil
+}
+
+// parseCond parses a parenthesized condition of a control flow statement.
+func (p *Parser) parseCond(keyword string) (ast.Expr, error) {
+	offset, ok := p.lexer.Read('(')
+	if !ok {
+		return nil, syntaxError(offset, "expected ( after %s", keyword)
+	}
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	offset, ok = p.lexer.Read(')')
+	if !ok {
+		return nil, syntaxError(offset, "expected )")
+	}
+	return cond, nil
+}
+
+func (p *Parser) parseIf(pos token.Pos) (*ast.IfStmt, error) {
+	cond, err := p.parseCond("if")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBranch()
+	if err != nil {
+		return nil, err
+	}
+	n := &ast.IfStmt{If: pos, Cond: cond, Body: body}
+
+	if _, ok := p.lexer.ReadKeyword("else"); !ok {
+		return n, nil
+	}
+	if ifOffset, ok := p.lexer.ReadKeyword("if"); ok {
+		n.Else, err = p.parseIf(p.offsetToPos(ifOffset))
+	} else {
+		n.Else, err = p.parseBranch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseWhile parses a while loop. Loops are represented as a for statement with only a condition.
+func (p *Parser) parseWhile(pos token.Pos) (*ast.ForStmt, error) {
+	cond, err := p.parseCond("while")
+	if err != nil {
+		return nil, err
+	}
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	body, err := p.parseBranch()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ForStmt{For: pos, Cond: cond, Body: body}, nil
+}
+
+// parseFor parses a loop with an init statement, a condition and a post statement, each of which may be missing: for (init; cond; post) body.
+func (p *Parser) parseFor(pos token.Pos) (*ast.ForStmt, error) {
+	offset, ok := p.lexer.Read('(')
+	if !ok {
+		return nil, syntaxError(offset, "expected ( after for")
+	}
+	n := &ast.ForStmt{For: pos}
+
+	// The init statement reads its own semicolon
+	initOffset := p.lexer.position
+	init, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	switch init.(type) {
+	case nil, *ast.VarDecl, *ast.AssignStmt, *ast.IncDecStmt:
+		n.Init = init
+	default:
+		p.lexer.position = initOffset
+		p.lexer.skipWhitespace()
+		return nil, syntaxError(p.lexer.position, "expected a declaration or an assignment")
+	}
+
+	if _, ok := p.lexer.Read(';'); !ok {
+		n.Cond, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if offset, ok := p.lexer.Read(';'); !ok {
+			return nil, syntaxError(offset, "expected ; after condition")
+		}
+	}
+
+	if _, ok := p.lexer.Read(')'); !ok {
+		id, err := p.parseID()
+		if err != nil {
+			return nil, err
+		}
+		n.Post, err = p.parseAssignment(id)
+		if err != nil {
+			return nil, err
+		}
+		if offset, ok := p.lexer.Read(')'); !ok {
+			return nil, syntaxError(offset, "expected )")
+		}
+	}
+
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	n.Body, err = p.parseBranch()
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseSwitch parses a switch over an int: switch (tag) { case 1, 2: ... default: ... }. The statements of a case run until the next case.
+func (p *Parser) parseSwitch(pos token.Pos) (*ast.SwitchStmt, error) {
+	tag, err := p.parseCond("switch")
+	if err != nil {
+		return nil, err
+	}
+	offset, ok := p.lexer.Read('{')
+	if !ok {
+		return nil, syntaxError(offset, "expected { after switch")
+	}
+	p.depth++
+	p.switchDepth++
+	defer func() {
+		p.depth--
+		p.switchDepth--
+	}()
+
+	n := &ast.SwitchStmt{Switch: pos, Tag: tag}
+	hasDefault := false
+	for {
+		if offset, ok := p.lexer.Read('}'); ok {
+			n.Rbrace = p.offsetToPos(offset)
+			return n, nil
+		}
+		c, err := p.parseCase()
+		if err != nil {
+			return nil, err
+		}
+		if c.List == nil {
+			if hasDefault {
+				return nil, syntaxError(p.PosToOffset(c.Case), "multiple defaults in switch")
+			}
+			hasDefault = true
+		}
+		n.Cases = append(n.Cases, c)
+	}
+}
+
+// parseCase parses a case or the default of a switch, with its statements.
+func (p *Parser) parseCase() (*ast.CaseClause, error) {
+	c := &ast.CaseClause{}
+	if offset, ok := p.lexer.ReadKeyword("default"); ok {
+		c.Case = p.offsetToPos(offset)
+	} else if offset, ok := p.lexer.ReadKeyword("case"); ok {
+		c.Case = p.offsetToPos(offset)
+		for {
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			c.List = append(c.List, value)
+			if _, ok := p.lexer.Read(','); !ok {
+				break
+			}
+		}
+	} else {
+		_, offset, _, _ := p.lexer.ReadToken()
+		return nil, syntaxError(offset, "expected case, default or }")
+	}
+
+	offset, ok := p.lexer.Read(':')
+	if !ok {
+		return nil, syntaxError(offset, "expected :")
+	}
+	c.Colon = p.offsetToPos(offset)
+	c.Body = &ast.BlockStmt{Lbrace: c.Colon}
+	for {
+		if id, _ := p.lexer.PeekID(); id == "case" || id == "default" {
+			return c, nil
+		}
+		p.lexer.skipWhitespace()
+		if b, _ := p.lexer.peek(); b == '}' || p.lexer.IsEOF() {
+			return c, nil
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			c.Body.List = append(c.Body.List, stmt)
+		}
+	}
+}
+
+// parseLoopBranch parses a break or continue statement. A break may also leave a switch.
+func (p *Parser) parseLoopBranch(pos token.Pos, offset int, tok token.Token) (*ast.BranchStmt, error) {
+	if tok == token.BREAK && p.loopDepth == 0 && p.switchDepth == 0 {
+		return nil, syntaxError(offset, "break outside of a loop or switch")
+	}
+	if tok == token.CONTINUE && p.loopDepth == 0 {
+		return nil, syntaxError(offset, "continue outside of a loop")
+	}
+	err := p.parseSemicolon()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BranchStmt{TokPos: pos, Tok: tok}, nil
+}
+
+// parseBranch parses the statement of an if branch or a loop body. The statement is always wrapped in a block, so names defined in it do not leak out of the branch.
+func (p *Parser) parseBranch() (*ast.BlockStmt, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	offset := p.lexer.position
+	n, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if block, ok := n.(*ast.BlockStmt); ok {
+		return block, nil
+	}
+	block := &ast.BlockStmt{Lbrace: p.offsetToPos(offset)}
+	if n != nil {
+		block.List = append(block.List, n)
+	}
+	return block, nil
+}
+
+// parseFunction parses a function declaration, starting from the parameter list.
+func (p *Parser) parseFunction(typ ast.TypeExpr, name *ast.Ident) (*ast.FuncDecl, error) {
+	if p.depth > 0 {
+		return nil, syntaxError(p.PosToOffset(name.NamePos), "functions can only be declared at the top level")
+	}
+
+	var params []*ast.Field
+	for {
+		if _, ok := p.lexer.Read(')'); ok && len(params) == 0 {
+			break
+		}
+		typ, typeOffset, ok := p.lexer.ReadID()
+		if !ok || !primitiveTypes[typ] {
+			return nil, syntaxError(typeOffset, "expected parameter type int, flt or bool")
+		}
+		param, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, &ast.Field{Type: &ast.Ident{Name: typ, NamePos: p.offsetToPos(typeOffset)}, Name: param})
+
+		if _, ok := p.lexer.Read(','); ok {
+			continue
+		}
+		if offset, ok := p.lexer.Read(')'); !ok {
+			return nil, syntaxError(offset, "expected , or ) after parameter")
+		}
+		break
+	}
+
+	offset, ok := p.lexer.Read('{')
+	if !ok {
+		return nil, syntaxError(offset, "expected { before function body")
+	}
+	body, err := p.parseBlock(p.offsetToPos(offset), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.FuncDecl{Result: typ, Name: name, Params: params, Body: body}, nil
+}
+
+// parseStruct parses a struct declaration. Fields are declared like variables, without a value.
+func (p *Parser) parseStruct(pos token.Pos, offset int) (*ast.StructDecl, error) {
+	if p.depth > 0 {
+		return nil, syntaxError(offset, "structs can only be declared at the top level")
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	lOffset, ok := p.lexer.Read('{')
+	if !ok {
+		return nil, syntaxError(lOffset, "expected { after struct name")
+	}
+
+	var fields []*ast.Field
+	for {
+		if _, ok := p.lexer.Read('}'); ok {
+			break
+		}
+		typ, err := p.parseID()
+		if err != nil {
+			return nil, err
+		}
+		field, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		offset, ok := p.lexer.Read(';')
+		if !ok {
+			return nil, syntaxError(offset, "expected ; after field")
+		}
+		fields = append(fields, &ast.Field{Type: typ, Name: field})
+	}
+
+	return &ast.StructDecl{Struct: pos, Name: name, Fields: fields}, nil
+}
+
+// parseImport parses an import of a module, named by a string literal.
+func (p *Parser) parseImport(pos token.Pos, offset int) (*ast.ImportDecl, error) {
+	if p.depth > 0 {
+		return nil, syntaxError(offset, "imports can only be declared at the top level")
+	}
+	path, pathOffset, ok, err := p.lexer.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, syntaxError(pathOffset, "expected the path of the imported module")
+	}
+	if offset, ok := p.lexer.Read(';'); !ok {
+		return nil, syntaxError(offset, "expected ; after import")
+	}
+	return &ast.ImportDecl{Import: pos, Path: &ast.BasicLit{ValuePos: p.offsetToPos(pathOffset), Kind: token.STRING, Value: path}}, nil
+}
+
+// parseExport parses a function or a struct declaration following the export keyword, which makes it visible to the modules that import it.
+func (p *Parser) parseExport(pos token.Pos, offset int) (ast.Stmt, error) {
+	if p.depth > 0 {
+		return nil, syntaxError(offset, "only top level declarations can be exported")
+	}
+	n, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	switch d := n.(type) {
+	case *ast.FuncDecl:
+		d.Export = pos
+	case *ast.StructDecl:
+		d.Export = pos
+	default:
+		return nil, syntaxError(offset, "only functions and structs can be exported")
+	}
+	return n, nil
 }
 
 func (p *Parser) parseReturn(pos token.Pos) (*ast.ReturnStmt, error) {
@@ -198,11 +837,32 @@ func (p *Parser) parseReturn(pos token.Pos) (*ast.ReturnStmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.ReturnStmt{Return: pos, Results: []ast.Expr{expr}}, nil
+	return &ast.ReturnStmt{Return: pos, Result: expr}, nil
 }
 
+// parseExpr parses an expression. The conditional expression c ? x : y has the lowest precedence, and groups to the right.
 func (p *Parser) parseExpr() (ast.Expr, error) {
-	return p.parseBinary()
+	cond, err := p.parseBinary()
+	if err != nil {
+		return nil, err
+	}
+	qOffset, ok := p.lexer.Read('?')
+	if !ok {
+		return cond, nil
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	cOffset, ok := p.lexer.Read(':')
+	if !ok {
+		return nil, syntaxError(cOffset, "expected : in conditional expression")
+	}
+	y, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CondExpr{Cond: cond, Question: p.offsetToPos(qOffset), X: x, Colon: p.offsetToPos(cOffset), Y: y}, nil
 }
 
 func opToToken(op string) token.Token {
@@ -231,6 +891,22 @@ func opToToken(op string) token.Token {
 		return token.NOT
 	case "!=":
 		return token.NEQ
+	case "&&":
+		return token.LAND
+	case "||":
+		return token.LOR
+	case "&":
+		return token.AND
+	case "|":
+		return token.OR
+	case "^":
+		return token.XOR
+	case "<<":
+		return token.SHL
+	case ">>":
+		return token.SHR
+	case ">>>":
+		return ast.LShr
 	}
 	return token.ILLEGAL
 }
@@ -238,7 +914,7 @@ func opToToken(op string) token.Token {
 // parseUnary parses the next unary operation(s). This is a recursive function that stops once a none-unary operation is met.
 func (p *Parser) parseUnary() (ast.Expr, error) {
 	if lOffset, ok := p.lexer.Read('('); ok {
-		expr, err := p.parseBinary()
+		expr, err := p.parseExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -311,8 +987,8 @@ func (p *Parser) withPrecedence(lhs ast.Expr, op token.Token, opPos token.Pos, r
 		return &ast.BinaryExpr{X: lhs, Y: rExpr, Op: rOp, OpPos: rOpPos}
 	}
 
-	rhs = &ast.BinaryExpr{X: mExpr, Y: rExpr, Op: rOp, OpPos: rOpPos}
-	return &ast.BinaryExpr{X: lExpr, Y: rhs, Op: lOp, OpPos: lOpPos}
+	// The rhs may bind tighter than operations inside mExpr as well, e.g. a && b < c + d
+	return &ast.BinaryExpr{X: lExpr, Y: p.withPrecedence(mExpr, rOp, rOpPos, rExpr), Op: lOp, OpPos: lOpPos}
 }
 
 func (p *Parser) offsetToPos(offset int) token.Pos {
@@ -323,21 +999,117 @@ func (p *Parser) PosToOffset(pos token.Pos) int {
 	return p.file.Offset(pos)
 }
 
-// parsePrimary parses a primary expression, which is either a number or an identifier.
+// parsePrimary parses a primary expression, which is either a number, a string, an identifier, a call or an allocation, followed by any field accesses, indexing or array lengths.
 func (p *Parser) parsePrimary() (ast.Expr, error) {
+	str, offset, ok, err := p.lexer.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return p.parsePostfix(&ast.BasicLit{ValuePos: p.offsetToPos(offset), Kind: token.STRING, Value: str})
+	}
 	num, offset, err := p.lexer.ReadNumber()
 	if err != nil {
 		if errors.Is(err, NANError) {
-			return p.parseID()
+			id, err := p.parseID()
+			if err != nil {
+				return nil, err
+			}
+			if keywords[id.Name] && !exprKeywords[id.Name] {
+				return nil, syntaxError(offset, "unexpected keyword %s", id.Name)
+			}
+			var x ast.Expr = id
+			if id.Name == "new" {
+				x, err = p.parseNew(id.NamePos)
+			} else if lOffset, ok := p.lexer.Read('('); ok {
+				x, err = p.parseCall(id, lOffset)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return p.parsePostfix(x)
 		}
 		return nil, syntaxError(offset, err.Error())
 	}
-	return &ast.BasicLit{ValuePos: p.offsetToPos(offset), Kind: token.INT, Value: num}, nil
+	kind := token.INT
+	if isFloat(num) {
+		kind = token.FLOAT
+	}
+	return &ast.BasicLit{ValuePos: p.offsetToPos(offset), Kind: kind, Value: num}, nil
 }
 
-// string creates a string representation of the node n. Used for debugging.
-func (p *Parser) string(n ast.Node) string {
-	sb := &strings.Builder{}
-	printer.Fprint(sb, p.fset, n)
-	return sb.String()
+// parseNew parses an allocation of a struct or an array.
+func (p *Parser) parseNew(pos token.Pos) (*ast.NewExpr, error) {
+	name, err := p.parseID()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.lexer.Read('['); !ok {
+		return &ast.NewExpr{New: pos, Type: name}, nil
+	}
+	length, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if offset, ok := p.lexer.Read(']'); !ok {
+		return nil, syntaxError(offset, "expected ]")
+	}
+	return &ast.NewExpr{New: pos, Type: name, Len: length}, nil
+}
+
+// parsePostfix parses the field accesses, indexing and array lengths following x, if there are any.
+func (p *Parser) parsePostfix(x ast.Expr) (ast.Expr, error) {
+	for {
+		if _, ok := p.lexer.Read('.'); ok {
+			field, err := p.parseID()
+			if err != nil {
+				return nil, err
+			}
+			x = &ast.FieldExpr{X: x, Field: field}
+			continue
+		}
+		if offset, ok := p.lexer.Read('#'); ok {
+			x = &ast.LenExpr{X: x, Hash: p.offsetToPos(offset)}
+			continue
+		}
+		if lOffset, ok := p.lexer.Read('['); ok {
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			rOffset, ok := p.lexer.Read(']')
+			if !ok {
+				return nil, syntaxError(rOffset, "expected ]")
+			}
+			x = &ast.IndexExpr{X: x, Lbrack: p.offsetToPos(lOffset), Index: index, Rbrack: p.offsetToPos(rOffset)}
+			continue
+		}
+		return x, nil
+	}
+}
+
+// parseCall parses the arguments of a call to the function fun.
+func (p *Parser) parseCall(fun *ast.Ident, lparenOffset int) (*ast.CallExpr, error) {
+	call := &ast.CallExpr{Fun: fun, Lparen: p.offsetToPos(lparenOffset)}
+	if offset, ok := p.lexer.Read(')'); ok {
+		call.Rparen = p.offsetToPos(offset)
+		return call, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if _, ok := p.lexer.Read(','); ok {
+			continue
+		}
+		offset, ok := p.lexer.Read(')')
+		if !ok {
+			return nil, syntaxError(offset, "expected , or ) after argument")
+		}
+		call.Rparen = p.offsetToPos(offset)
+		return call, nil
+	}
 }
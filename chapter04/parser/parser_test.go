@@ -2,9 +2,9 @@ package parser
 
 import (
 	"fmt"
-	goast "go/ast"
 	"testing"
 
+	sast "github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/utils/ast"
 	"github.com/stretchr/testify/suite"
 )
@@ -13,28 +13,39 @@ type ParserTestSuite struct {
 	suite.Suite
 }
 
-func (suite *ParserTestSuite) equalAST(expected goast.Node, given goast.Node, failMsg string) {
+func (suite *ParserTestSuite) equalAST(expected sast.Node, given sast.Node, failMsg string) {
 	suite.IsType(expected, given, failMsg)
 	switch e := expected.(type) {
-	case *goast.BinaryExpr:
-		g := given.(*goast.BinaryExpr)
+	case *sast.BinaryExpr:
+		g := given.(*sast.BinaryExpr)
 		suite.equalAST(e.X, g.X, failMsg)
 		suite.equalAST(e.Y, g.Y, failMsg)
 		suite.Equal(e.Op, g.Op, failMsg)
 		suite.NotZero(g.OpPos, failMsg)
-	case *goast.UnaryExpr:
-		g := given.(*goast.UnaryExpr)
+	case *sast.UnaryExpr:
+		g := given.(*sast.UnaryExpr)
 		suite.equalAST(e.X, g.X, failMsg)
 		suite.Equal(e.Op, g.Op, failMsg)
 		suite.NotZero(g.OpPos, failMsg)
-	case *goast.BasicLit:
-		g := given.(*goast.BasicLit)
+	case *sast.BasicLit:
+		g := given.(*sast.BasicLit)
 		suite.Equal(e.Value, g.Value, failMsg)
 		suite.Equal(e.Kind, g.Kind, failMsg)
 		suite.NotZero(g.ValuePos, failMsg)
-	case *goast.ParenExpr:
-		g := given.(*goast.ParenExpr)
+	case *sast.ParenExpr:
+		g := given.(*sast.ParenExpr)
 		suite.equalAST(e.X, g.X, failMsg)
+	case *sast.CondExpr:
+		g := given.(*sast.CondExpr)
+		suite.equalAST(e.Cond, g.Cond, failMsg)
+		suite.equalAST(e.X, g.X, failMsg)
+		suite.equalAST(e.Y, g.Y, failMsg)
+		suite.NotZero(g.Question, failMsg)
+		suite.NotZero(g.Colon, failMsg)
+	case *sast.Ident:
+		g := given.(*sast.Ident)
+		suite.Equal(e.Name, g.Name, failMsg)
+		suite.NotZero(g.NamePos, failMsg)
 	default:
 		suite.FailNow("Unexpected type", "Type: %T", e)
 	}
@@ -44,7 +55,7 @@ func (suite *ParserTestSuite) TestPrecedence() {
 	subTests := []struct {
 		name     string
 		input    string
-		expected goast.Node
+		expected sast.Node
 	}{
 		{
 			name:     "mul->add",
@@ -131,6 +142,41 @@ func (suite *ParserTestSuite) TestPrecedence() {
 			input:    "1*2+3/-4-5",
 			expected: ast.Bin(ast.Bin(ast.Bin(1, "*", 2), "+", ast.Bin(3, "/", ast.Un("-", 4))), "-", 5),
 		},
+		{
+			name:     "cmp->add->mul",
+			input:    "1<2+3*4",
+			expected: ast.Bin(1, "<", ast.Bin(2, "+", ast.Bin(3, "*", 4))),
+		},
+		{
+			name:     "and->cmp",
+			input:    "1<2&&3<4",
+			expected: ast.Bin(ast.Bin(1, "<", 2), "&&", ast.Bin(3, "<", 4)),
+		},
+		{
+			name:     "or->and",
+			input:    "1||2&&3||4",
+			expected: ast.Bin(ast.Bin(1, "||", ast.Bin(2, "&&", 3)), "||", 4),
+		},
+		{
+			name:     "and->cmp->add",
+			input:    "1&&2<3+4",
+			expected: ast.Bin(1, "&&", ast.Bin(2, "<", ast.Bin(3, "+", 4))),
+		},
+		{
+			name:     "shift->add",
+			input:    "1<<2+3",
+			expected: ast.Bin(ast.Bin(1, "<<", 2), "+", 3),
+		},
+		{
+			name:     "or->and",
+			input:    "1|2&3>>>4",
+			expected: ast.Bin(1, "|", ast.Bin(ast.Bin(2, "&", 3), ">>>", 4)),
+		},
+		{
+			name:     "cmp->xor",
+			input:    "1==2^3",
+			expected: ast.Bin(1, "==", ast.Bin(2, "^", 3)),
+		},
 		{
 			name:     "paren",
 			input:    "1*(2+3)",
@@ -148,12 +194,236 @@ func (suite *ParserTestSuite) TestPrecedence() {
 			p := NewParser(test.input)
 			n, err := p.parseBinary()
 			suite.NoError(err)
-			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, p.string(n))
+			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, sast.ExprString(n))
 			suite.equalAST(test.expected, n, failMsg)
 		})
 	}
 }
 
+func (suite *ParserTestSuite) TestComments() {
+	subTests := []struct {
+		name     string
+		input    string
+		expected [][]string
+		offsets  [][]int
+	}{
+		{name: "line", input: "return 1; // one", expected: [][]string{{"// one"}}, offsets: [][]int{{10}}},
+		{name: "block", input: "return /* a */ 1;", expected: [][]string{{"/* a */"}}, offsets: [][]int{{7}}},
+		{name: "multi-line block", input: "/* a\n b */ return 1;", expected: [][]string{{"/* a\n b */"}}, offsets: [][]int{{0}}},
+		{name: "grouped", input: "// a\n// b\nreturn 1;", expected: [][]string{{"// a", "// b"}}, offsets: [][]int{{0, 5}}},
+		{name: "blank line", input: "// a\n\n// b\nreturn 1;", expected: [][]string{{"// a"}, {"// b"}}, offsets: [][]int{{0}, {6}}},
+		{name: "code between", input: "int a = 1; // a\nreturn a; // b", expected: [][]string{{"// a"}, {"// b"}}, offsets: [][]int{{11}, {26}}},
+		{name: "division", input: "return 4 / /**/ 2 // 2\n;", expected: [][]string{{"/**/"}, {"// 2"}}, offsets: [][]int{{11}, {18}}},
+		{name: "backtracking", input: "if /* c */ (arg) return 1; return 2;", expected: [][]string{{"/* c */"}}, offsets: [][]int{{3}}},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			suite.Require().NoError(err)
+			groups := p.Comments()
+			suite.Require().Len(groups, len(test.expected))
+			for i, group := range groups {
+				suite.Require().Len(group.List, len(test.expected[i]))
+				for j, c := range group.List {
+					suite.Equal(test.expected[i][j], c.Text)
+					suite.Equal(test.offsets[i][j], p.PosToOffset(c.Slash))
+				}
+			}
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestPositions() {
+	subTests := []struct {
+		name  string
+		input string
+	}{
+		{name: "declarations", input: "int a = 1; var b = a; val c = b; flt d; int[] e = new int[3]; return e[0] + e#;"},
+		{name: "control flow", input: "while (arg) { if (arg > 1) break; else { arg -= 1; continue; } } return -arg;"},
+		{name: "functions", input: "int sq(int x) { return x * x; } return sq(arg);"},
+		{name: "structs", input: "struct P { int x; } P p = new P; p.x++; #showGraph return (p.x);"},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			n, err := p.Parse()
+			suite.Require().NoError(err)
+			sast.Inspect(n, func(n sast.Node) bool {
+				if n != nil {
+					suite.True(n.Pos().IsValid(), "%T has no position", n)
+				}
+				return true
+			})
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestUnterminatedComment() {
+	subTests := []struct {
+		name   string
+		input  string
+		offset int
+	}{
+		{name: "statement", input: "return 1; /* one", offset: 10},
+		{name: "expression", input: "return 1 + /* one;", offset: 11},
+		{name: "only opening", input: "/*", offset: 0},
+		{name: "shared star", input: "return 1; /*/", offset: 10},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var syntaxErr *SyntaxError
+			suite.Require().ErrorAs(err, &syntaxErr)
+			suite.ErrorContains(err, "comment not terminated")
+			suite.Equal(test.offset, syntaxErr.Offset)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestInvalidNumbers() {
+	subTests := []struct {
+		name   string
+		input  string
+		error  string
+		offset int
+	}{
+		{name: "leading zero", input: "return 012;", error: "integer values cannot start with '0'", offset: 7},
+		{name: "empty hex", input: "return 0x;", error: "hexadecimal literal has no digits", offset: 7},
+		{name: "empty binary", input: "return 0b_;", error: "binary literal has no digits", offset: 7},
+		{name: "binary digit", input: "return 0b1012;", error: "invalid digit '2' in binary literal", offset: 12},
+		{name: "trailing separator", input: "return 1_;", error: "'_' must separate successive digits", offset: 7},
+		{name: "double separator", input: "return 0x1__0;", error: "'_' must separate successive digits", offset: 7},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var syntaxErr *SyntaxError
+			suite.Require().ErrorAs(err, &syntaxErr)
+			suite.ErrorContains(err, test.error)
+			suite.Equal(test.offset, syntaxErr.Offset)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestInvalidStrings() {
+	subTests := []struct {
+		name   string
+		input  string
+		error  string
+		offset int
+	}{
+		{name: "not terminated", input: `return "abc`, error: "string literal not terminated", offset: 7},
+		{name: "newline", input: "return \"a\nb\";", error: "string literal not terminated", offset: 7},
+		{name: "unknown escape", input: `return "a\qb";`, error: "invalid escape sequence", offset: 9},
+		{name: "short hex escape", input: `return "\x4";`, error: "invalid escape sequence", offset: 8},
+		{name: "invalid UTF-8", input: "return \"a\xffb\";", error: "invalid UTF-8 encoding", offset: 9},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var syntaxErr *SyntaxError
+			suite.Require().ErrorAs(err, &syntaxErr)
+			suite.ErrorContains(err, test.error)
+			suite.Equal(test.offset, syntaxErr.Offset)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestInvalidModules() {
+	subTests := []struct {
+		name   string
+		input  string
+		error  string
+		offset int
+	}{
+		{name: "nested import", input: `{ import "a"; }`, error: "imports can only be declared at the top level", offset: 2},
+		{name: "import path", input: "import a;", error: "expected the path of the imported module", offset: 7},
+		{name: "import semicolon", input: `import "a" return 1;`, error: "expected ; after import", offset: 11},
+		{name: "export variable", input: "export int x = 1;", error: "only functions and structs can be exported", offset: 0},
+		{name: "nested export", input: "{ export int f() { return 1; } }", error: "only top level declarations can be exported", offset: 2},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var syntaxErr *SyntaxError
+			suite.Require().ErrorAs(err, &syntaxErr)
+			suite.ErrorContains(err, test.error)
+			suite.Equal(test.offset, syntaxErr.Offset)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestRecovery() {
+	subTests := []struct {
+		name    string
+		input   string
+		errors  []string
+		offsets []int
+	}{
+		{
+			name:    "statements",
+			input:   "int a = ; int b = 2; return a +;",
+			errors:  []string{"expected identifier", "expected identifier"},
+			offsets: []int{8, 31},
+		},
+		{
+			name:    "missing semicolons",
+			input:   "int a = 1\nint b = 2;\nreturn a\n}",
+			errors:  []string{"expected ; after expression", "expected ; after expression"},
+			offsets: []int{10, 30},
+		},
+		{
+			name:    "in block",
+			input:   "{ int a = ; } return 1 1;",
+			errors:  []string{"expected identifier", "expected ; after expression"},
+			offsets: []int{10, 23},
+		},
+		{
+			name:    "skipped block",
+			input:   "while (1 +) { int a = 1; } return 1 +;",
+			errors:  []string{"expected identifier", "expected identifier"},
+			offsets: []int{10, 37},
+		},
+		{
+			name:    "unclosed block",
+			input:   "{ return ; ",
+			errors:  []string{"expected identifier", "expected a statement got "},
+			offsets: []int{9, 11},
+		},
+		{
+			name:    "break after loop error",
+			input:   "while (arg) { int = 1; } break;",
+			errors:  []string{"expected identifier", "break outside of a loop"},
+			offsets: []int{18, 25},
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var errs SyntaxErrors
+			suite.Require().ErrorAs(err, &errs)
+			suite.Require().Len(errs, len(test.errors))
+			for i, e := range errs {
+				suite.ErrorContains(e, test.errors[i])
+				suite.Equal(test.offsets[i], e.Offset)
+			}
+		})
+	}
+}
+
 func (suite *ParserTestSuite) TestMissingSemicolon() {
 	subTests := []struct {
 		name  string
@@ -193,17 +463,111 @@ func (suite *ParserTestSuite) TestBlockNotClosed() {
 	}
 }
 
+func (suite *ParserTestSuite) TestUnicodeIdentifiers() {
+	subTests := []struct {
+		name     string
+		input    string
+		expected sast.Node
+	}{
+		{name: "latin", input: "größe*2", expected: ast.Bin("größe", "*", 2)},
+		{name: "greek", input: "π+1", expected: ast.Bin("π", "+", 1)},
+		{name: "cjk", input: "日本-語", expected: ast.Bin("日本", "-", "語")},
+		{name: "unicode digit", input: "x٣+1", expected: ast.Bin("x٣", "+", 1)},
+		{name: "underscore", input: "_ä", expected: ast.ID("_ä")},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			n, err := p.parseBinary()
+			suite.NoError(err)
+			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, sast.ExprString(n))
+			suite.equalAST(test.expected, n, failMsg)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestInvalidUnicode() {
+	subTests := []struct {
+		name   string
+		input  string
+		error  string
+		offset int
+	}{
+		{name: "symbol", input: "int € = 1;", error: "expected identifier", offset: 4},
+		{name: "digit start", input: "return 1; ٣x = 1;", error: "expected a statement got ٣", offset: 10},
+		{name: "after identifier", input: "int äb = 1; return äb; €", error: "expected a statement got €", offset: 25},
+		{name: "invalid encoding", input: "return 1; \xff", error: "invalid UTF-8 encoding", offset: 10},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			_, err := p.Parse()
+			var syntaxErr *SyntaxError
+			suite.Require().ErrorAs(err, &syntaxErr)
+			suite.ErrorContains(err, test.error)
+			suite.Equal(test.offset, syntaxErr.Offset)
+		})
+	}
+}
+
 func (suite *ParserTestSuite) TestExpr() {
 	subTests := []struct {
 		name     string
 		input    string
-		expected goast.Node
+		expected sast.Node
 	}{
 		{
 			name:     "equals minus",
 			input:    "1==-1",
 			expected: ast.Bin(1, "==", ast.Un("-", 1)),
 		},
+		{
+			name:     "float",
+			input:    "1.5*2",
+			expected: ast.Bin(ast.Flt("1.5"), "*", 2),
+		},
+		{
+			name:     "exponent",
+			input:    "2e10+2.5E-3",
+			expected: ast.Bin(ast.Flt("2e10"), "+", ast.Flt("2.5E-3")),
+		},
+		{
+			name:     "hex",
+			input:    "0x1F+0XaB",
+			expected: ast.Bin(ast.Int("0x1F"), "+", ast.Int("0XaB")),
+		},
+		{
+			name:     "binary",
+			input:    "0b101*0B1",
+			expected: ast.Bin(ast.Int("0b101"), "*", ast.Int("0B1")),
+		},
+		{
+			name:     "separators",
+			input:    "1_000-0x_FF_FF",
+			expected: ast.Bin(ast.Int("1_000"), "-", ast.Int("0x_FF_FF")),
+		},
+		{
+			name:     "hex exponent letter",
+			input:    "0xE+1",
+			expected: ast.Bin(ast.Int("0xE"), "+", 1),
+		},
+		{
+			name:     "zero fraction",
+			input:    "0.5-0",
+			expected: ast.Bin(ast.Flt("0.5"), "-", 0),
+		},
+		{
+			name:     "string",
+			input:    `"a\tb\x41"=="\""`,
+			expected: ast.Bin(ast.Str(`"a\tb\x41"`), "==", ast.Str(`"\""`)),
+		},
+		{
+			name:     "unicode and empty strings",
+			input:    `"é"!=""`,
+			expected: ast.Bin(ast.Str(`"é"`), "!=", ast.Str(`""`)),
+		},
 	}
 
 	for _, test := range subTests {
@@ -211,7 +575,51 @@ func (suite *ParserTestSuite) TestExpr() {
 			p := NewParser(test.input)
 			n, err := p.parseBinary()
 			suite.NoError(err)
-			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, p.string(n))
+			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, sast.ExprString(n))
+			suite.equalAST(test.expected, n, failMsg)
+		})
+	}
+}
+
+func (suite *ParserTestSuite) TestCondExpr() {
+	subTests := []struct {
+		name     string
+		input    string
+		expected sast.Node
+	}{
+		{
+			name:     "simple",
+			input:    "a?1:2",
+			expected: ast.Cond("a", 1, 2),
+		},
+		{
+			name:     "lowest precedence",
+			input:    "a||b?1+2:3*4",
+			expected: ast.Cond(ast.Bin("a", "||", "b"), ast.Bin(1, "+", 2), ast.Bin(3, "*", 4)),
+		},
+		{
+			name:     "right associative",
+			input:    "a?1:b?2:3",
+			expected: ast.Cond("a", 1, ast.Cond("b", 2, 3)),
+		},
+		{
+			name:     "nested in then",
+			input:    "a?b?1:2:3",
+			expected: ast.Cond("a", ast.Cond("b", 1, 2), 3),
+		},
+		{
+			name:     "paren",
+			input:    "(a?1:2)+3",
+			expected: ast.Bin(ast.Paren(ast.Cond("a", 1, 2)), "+", 3),
+		},
+	}
+
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			p := NewParser(test.input)
+			n, err := p.parseExpr()
+			suite.NoError(err)
+			failMsg := fmt.Sprintf("input:\n\t%s\nparsed:\n\t%s\n", test.input, sast.ExprString(n))
 			suite.equalAST(test.expected, n, failMsg)
 		})
 	}
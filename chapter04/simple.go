@@ -1,69 +1,149 @@
 package simple
 
 import (
-	goParser "go/parser"
+	"fmt"
+	"go/token"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir"
 	"github.com/SeaOfNodes/Simple-Go/chapter04/ir/types"
-	"github.com/SeaOfNodes/Simple-Go/chapter04/parser"
+)
+
+// ContextLines is the number of lines shown before the line of an error.
+var ContextLines = 2
+
+// ANSI escape codes used to colorize errors
+const (
+	bold  = "\033[1m"
+	red   = "\033[31m"
+	reset = "\033[0m"
 )
 
 type SourceError struct {
 	internal error
 	source   string
 	offset   int
+	// file holds the name and the lines of the source
+	file *token.File
 }
 
 func (s *SourceError) Error() string {
-	msg := "\n" + s.source + "\n"
-	msg += strings.Repeat(" ", s.offset) + "^\n"
-	msg += s.internal.Error()
-	return msg
+	return s.Render(false)
 }
 
-func getArgType(arg any) types.Type {
-	switch t := arg.(type) {
-	case int:
-		return types.NewInt(t)
-	default:
-		return types.Bottom
+// Render renders the error as file:line:col followed by the message, and the line of the error with a few lines of context. The caret under the line is aligned with the same tabs as the line. With color, ANSI escape codes highlight the location, the message and the caret.
+func (s *SourceError) Render(color bool) string {
+	style := func(codes string, text string) string {
+		if !color {
+			return text
+		}
+		return codes + text + reset
 	}
-}
 
-func Simple(source string, arg any) (*ir.ReturnNode, *ir.Generator, error) {
-	p := parser.NewParser(source)
-	n, err := p.Parse()
-	if err != nil {
-		// Enrich syntax errors with source info
-		if s, ok := err.(*parser.SyntaxError); ok {
-			return nil, nil, &SourceError{s, source, s.Offset}
-		}
-		return nil, nil, err
+	sb := &strings.Builder{}
+	sb.WriteString(style(bold, s.Location()+":"))
+	sb.WriteString(" ")
+	sb.WriteString(style(bold+red, s.internal.Error()))
+	sb.WriteString("\n")
+
+	line := s.Line()
+	width := len(strconv.Itoa(line))
+	for l := max(1, line-ContextLines); l <= line; l++ {
+		fmt.Fprintf(sb, "%*d | %s\n", width, l, s.lineText(l))
 	}
 
-	generator := ir.NewGenerator(getArgType(arg))
-	ret, err := generator.Generate(n)
-	if err != nil {
-		// Enrich ast errors with source info
-		if a, ok := err.(*ir.ASTError); ok {
-			return nil, nil, &SourceError{a, source, p.PosToOffset(a.Pos)}
+	// Runes before the error are replaced with spaces, but tabs are kept so the caret lines up with the source
+	lineStart := s.offset - (s.file.Position(s.pos()).Column - 1)
+	caret := []rune(s.source[lineStart:s.offset])
+	for i, r := range caret {
+		if r != '\t' {
+			caret[i] = ' '
 		}
-		return nil, nil, err
 	}
-	return ret, generator, nil
+	fmt.Fprintf(sb, "%*s | %s%s", width, "", string(caret), style(bold+red, "^"))
+	return sb.String()
+}
+
+func (s *SourceError) pos() token.Pos {
+	return s.file.Pos(s.offset)
+}
+
+// Location returns the position of the error as file:line:col, or line:col if the source has no file name.
+func (s *SourceError) Location() string {
+	loc := fmt.Sprintf("%d:%d", s.Line(), s.Column())
+	if name := s.file.Name(); name != "" {
+		return name + ":" + loc
+	}
+	return loc
+}
+
+// Line returns the line of the error, counted from 1.
+func (s *SourceError) Line() int {
+	return s.file.Line(s.pos())
+}
+
+// Column returns the column of the error in its line, counted in runes from 1.
+func (s *SourceError) Column() int {
+	// token.File counts columns in bytes
+	lineStart := s.offset - (s.file.Position(s.pos()).Column - 1)
+	return utf8.RuneCountInString(s.source[lineStart:s.offset]) + 1
+}
+
+// lineText returns the text of the line, without the line break.
+func (s *SourceError) lineText(line int) string {
+	start := s.file.Offset(s.file.LineStart(line))
+	text := s.source[start:]
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		text = text[:end]
+	}
+	return strings.TrimSuffix(text, "\r")
+}
+
+// Offset returns the byte offset of the error in the source.
+func (s *SourceError) Offset() int {
+	return s.offset
+}
+
+func (s *SourceError) Unwrap() error {
+	return s.internal
+}
+
+// SourceErrors are multiple errors in the same source, in source order. Simple returns it when the parser finds more than one syntax error in a file.
+type SourceErrors []*SourceError
+
+func (s SourceErrors) Error() string {
+	return s.Render(false)
 }
 
-func GoSimple(source string, arg any) (*ir.ReturnNode, *ir.Generator, error) {
-	n, err := goParser.ParseExpr(source)
-	if err != nil {
-		return nil, nil, err
+// Render renders every error, separated by empty lines.
+func (s SourceErrors) Render(color bool) string {
+	msgs := make([]string, len(s))
+	for i, err := range s {
+		msgs[i] = err.Render(color)
 	}
+	return strings.Join(msgs, "\n\n")
+}
 
-	generator := ir.NewGenerator(getArgType(arg))
-	retNode, err := generator.Generate(n)
-	if err != nil {
-		return nil, nil, err
+func (s SourceErrors) Unwrap() []error {
+	errs := make([]error, len(s))
+	for i, err := range s {
+		errs[i] = err
 	}
-	return retNode, generator, nil
+	return errs
+}
+
+func getArgType(arg any) types.Type {
+	switch t := arg.(type) {
+	case int:
+		return types.NewInt(t)
+	default:
+		return types.Bottom
+	}
+}
+
+// Simple compiles a program from a single source, which cannot import modules.
+func Simple(source string, arg any) (*ir.ReturnNode, *ir.Generator, error) {
+	return SimpleFile("", source, nil, arg)
 }
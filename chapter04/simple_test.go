@@ -1,6 +1,9 @@
 package simple_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	simple "github.com/SeaOfNodes/Simple-Go/chapter04"
@@ -23,12 +26,21 @@ func (suite *SimpleTestSuite) TestValidPrograms() {
 		{name: "Zero", input: "return 0;", num: 0},
 		{name: "MinusNumber", input: "return -2;", num: -2},
 		{name: "EmptyBlock", input: "{ } return -2;", num: -2},
+		{name: "LineComment", input: "// The answer\nreturn 42; // done", num: 42},
+		{name: "BlockComment", input: "return /* not 1 */ 2 /* but 2 */;", num: 2},
+		{name: "Hex", input: "return 0xFF;", num: 255},
+		{name: "Binary", input: "return 0b1010;", num: 10},
+		{name: "Separators", input: "return 1_000_000;", num: 1000000},
+		{name: "Unicode", input: "int größe = 3; int π = größe * 2; return π + 1;", num: 7},
+		{name: "MaxInt", input: "return 0x7FFF_FFFF_FFFF_FFFF;", num: 9223372036854775807},
+		{name: "MinInt", input: "return -9223372036854775808;", num: -9223372036854775808},
 	}
 	for _, test := range subTests {
 		suite.Run(test.name, func() {
 			ret, _, err := simple.Simple(test.input, nil)
 			suite.Require().NoError(err)
-			suite.Equal(ir.StartNode, ret.Control())
+			// Control is the projection of the start node
+			suite.Equal(ir.StartNode, ir.In(ret.Control(), 0))
 
 			expr := ret.Expr()
 			suite.IsType(&ir.ConstantNode{}, expr)
@@ -52,6 +64,14 @@ func (suite *SimpleTestSuite) TestInvalidPrograms() {
 		{name: "MissingWhitespace", input: "return123;", error: "Syntax error: expected assignment"},
 		{name: "ByteAfterSemicolon", input: "return 1;}", error: "Syntax error: expected a statement got }"},
 		{name: "SelfAssign", input: "int a=a; return a;", error: "Compute error: unknown identifier"},
+		{name: "Overflow", input: "return 1 + 9223372036854775808;", error: "1:12: Compute error: integer literal out of range: 9223372036854775808"},
+		{name: "HexOverflow", input: "return 0x1_0000_0000_0000_0000;", error: "1:8: Compute error: integer literal out of range: 0x1_0000_0000_0000_0000"},
+		{name: "NegativeOverflow", input: "return -9223372036854775809;", error: "Compute error: integer literal out of range: -9223372036854775809"},
+		{name: "BinaryDigit", input: "return 0b102;", error: "1:12: Syntax error: invalid digit '2' in binary literal"},
+		{name: "UnterminatedComment", input: "return 1; /* done", error: "1:11: Syntax error: comment not terminated"},
+		{name: "DivideByZero", input: "return 1/0;", error: "1:10: Compute error: divide by zero"},
+		{name: "CompoundDivideByZero", input: "int x = 0;\nx /= 0;\nreturn x;", error: "2:6: Compute error: divide by zero"},
+		{name: "KnownZeroDivisor", input: "int x = 0; return arg / x;", error: "1:25: Compute error: divide by zero"},
 	}
 	for _, test := range subTests {
 		suite.Run(test.name, func() {
@@ -83,6 +103,1186 @@ func (suite *SimpleTestSuite) TestVars() {
 	}
 }
 
+func (suite *SimpleTestSuite) TestIf() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "IfElse", input: "int a=1; if (arg==1) a=arg+2; else a=arg-3; return a;", output: "return Phi(Region15,(arg+2),(arg-3));"},
+		{name: "NoElse", input: "int a=1; if (arg==1) { a=2; } return a;", output: "return Phi(Region12,2,1);"},
+		{name: "SameValue", input: "int a=1; if (arg==1) a=2; else a=2; return a;", output: "return 2;"},
+		{name: "ElseIf", input: "int a=0; if (arg==1) a=1; else if (arg==2) a=2; else a=3; return a;", output: "return Phi(Region22,1,Phi(Region20,2,3));"},
+		{name: "ConstantTrue", input: "int a=1; if (1) a=2; else a=3; return a;", output: "return 2;"},
+		{name: "ConstantFalse", input: "int a=1; if (1==2) a=2; else a=3; return a;", output: "return 3;"},
+		{name: "ReturnInBranch", input: "if (arg==1) return 3; return 4;", output: "return Phi(Region11,3,4);"},
+		{name: "ReturnInBoth", input: "if (arg) return arg+1; else return 0;", output: "return Phi(Region10,(arg+1),0);"},
+		{name: "DeadReturn", input: "if (0) return 3; return 4;", output: "return 4;"},
+		{name: "BranchScope", input: "int a=1; if (arg) { int b=2; a=b; } return a;", output: "return Phi(Region10,2,1);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidIf() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "MissingParen", input: "if arg==1 return 1; return 2;", error: "Syntax error: expected ( after if"},
+		{name: "MissingCloseParen", input: "if (arg==1 return 1; return 2;", error: "Syntax error: expected )"},
+		{name: "BranchDecl", input: "if (arg) int a=1; return a;", error: "Compute error: unknown identifier"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestWhile() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Counter", input: "while(arg < 10) { arg = arg + 1; } return arg;", output: "return Phi(Loop4,arg,(Phi_arg+1));"},
+		{name: "Sum", input: "int sum=0; int i=0; while(i < arg) { sum = sum + i; i = i + 1; } return sum;", output: "return Phi(Loop6,0,(Phi(Loop6,0,(Phi_i+1))+Phi_sum));"},
+		{name: "SingleStatement", input: "int a=1; while(a < 10) a = a * 2; return a;", output: "return Phi(Loop5,1,(Phi_a*2));"},
+		{name: "BodyScope", input: "int a=1; while(arg < 10) { int b=arg; arg = b + 1; } return a;", output: "return 1;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestBreakContinue() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Break", input: "while(arg < 10) { arg = arg + 1; if (arg == 5) break; } return arg;", output: "return Phi(Region22,Phi(Loop4,arg,(Phi_arg+1)),(Phi(Loop4,arg,(Phi_arg+1))+1));"},
+		{name: "Continue", input: "int a=0; while(arg < 10) { arg = arg + 1; if (arg == 5) continue; a = a + 1; } return a;", output: "return Phi(Loop5,0,Phi(Region27,Phi_a,(Phi_a+1)));"},
+		{name: "MultipleBreaks", input: "int a=0; while(1) { arg = arg + 1; if (arg == 5) break; if (arg == 7) { int b=2; a=b; break; } } return a;", output: "return Phi(Region31,0,2);"},
+		{name: "ContinueInBlock", input: "while(arg<3) { { int x = 1; arg=arg+x; continue; } } return arg;", output: "return Phi(Loop4,arg,(Phi_arg+1));"},
+		{name: "Nested", input: "int a=0; while(arg<10) { arg=arg+1; while(1) { a=a+1; break; } } return a;", output: "return Phi(Loop5,0,(Phi_a+1));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidBreakContinue() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Break", input: "break; return 1;", error: "Syntax error: break outside of a loop"},
+		{name: "Continue", input: "if (arg) continue; return 1;", error: "Syntax error: continue outside of a loop"},
+		{name: "AfterLoop", input: "while(arg) arg=arg-1; break;", error: "Syntax error: break outside of a loop"},
+		{name: "MissingSemicolon", input: "while(arg) break", error: "Syntax error: expected ;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestFor() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Sum", input: "int s = 0; for (int i = 0; i < arg; i++) s += i; return s;", output: "return Phi(Loop6,0,(Phi(Loop6,0,(Phi_i+1))+Phi_s));"},
+		{name: "NoInit", input: "int i = 0; for (; i < arg; i += 2) {} return i;", output: "return Phi(Loop5,0,(Phi_i+2));"},
+		{name: "Forever", input: "for (;;) { arg--; if (arg < 0) break; } return arg;", output: "return (Phi(Loop4,arg,(Phi_arg-1))-1);"},
+		{name: "Continue", input: "int s = 0; for (int i = 0; i < 10; i++) { if (i == arg) continue; s++; } return s;", output: "return Phi(Loop6,0,Phi(Region26,Phi_s,(Phi_s+1)));"},
+		{name: "Scope", input: "int i = 5; for (int i = 0; i < arg; i++) {} return i;", output: "return 5;"},
+		{name: "EndlessPath", input: "if (arg) return 1; while (1) {}", output: "return 1;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidFor() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "MissingParen", input: "for int i = 0; i < 1; i++) {} return 0;", error: "Syntax error: expected ( after for"},
+		{name: "BadInit", input: "for (return 1; arg; arg++) {} return 0;", error: "Syntax error: expected a declaration or an assignment"},
+		{name: "MissingSemicolon", input: "for (;arg arg++) {} return 0;", error: "Syntax error: expected ; after condition"},
+		{name: "OutOfScope", input: "for (int i = 0; i < arg; i++) {} return i;", error: "Compute error: unknown identifier"},
+		{name: "Endless", input: "while (1) { } return 1;", error: "1:1: Compute error: loop never exits, so the program never returns"},
+		{name: "EndlessFor", input: "int a = 0;\nfor (;;) { a++; if (a < 0) continue; }\nreturn a;", error: "2:1: Compute error: loop never exits, so the program never returns"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestSwitch() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Cases", input: "int r; switch (arg) { case 1: r = 10; case 2, 3: r = 20; default: r = 30; } return r;", output: "return Phi(Region35,Phi(Region31,10,20),30);"},
+		{name: "NoDefault", input: "int r = 0; switch (arg) { case 1: r = 10; } return r;", output: "return Phi(Region15,10,0);"},
+		{name: "Constant", input: "int r = 0; switch (2) { case 1: r = 10; case 2: r = 20; default: r = 30; } return r;", output: "return 20;"},
+		{name: "Break", input: "int r = 0; switch (arg) { case 1: if (r == 0) break; r = 10; } return r;", output: "return 0;"},
+		{name: "Returns", input: "switch (arg) { case 1: return 10; default: return 20; }", output: "return Phi(Region12,10,20);"},
+		{name: "InLoop", input: "int s = 0; for (int i = 0; i < 4; i++) { switch (i) { case 1: continue; case 2: break; } s++; } return s;", output: "return Phi(Loop6,0,Phi(Region38,Phi_s,(Phi_s+1)));"},
+		{name: "ConstantValues", input: "val one = 1; int r = 0; switch (arg) { case one: r = 1; case one + 1: r = 2; } return r;", output: "return Phi(Region28,Phi(Region25,1,2),0);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestJumpTable() {
+	ir.JumpTables = true
+	defer func() { ir.JumpTables = false }()
+
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Dense", input: "int r = 0; switch (arg) { case 1: r = 10; case 2: r = 20; case 3, 4: r = 30; } return r;", output: "return Phi(Region31,Phi(Region28,Phi(Region24,10,20),30),0);"},
+		{name: "Constant", input: "int r = 0; switch (3) { case 1: r = 10; case 2: r = 20; case 3, 4: r = 30; } return r;", output: "return 30;"},
+		{name: "Sparse", input: "int r = 0; switch (arg) { case 1: r = 10; case 100: r = 20; case 1000: r = 30; } return r;", output: "return Phi(Region37,Phi(Region34,Phi(Region30,10,20),30),0);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidSwitch() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "NotConstant", input: "switch (arg) { case arg: return 1; } return 0;", error: "Compute error: case values must be int constants"},
+		{name: "Float", input: "switch (arg) { case 1.5: return 1; } return 0;", error: "Compute error: case values must be int constants"},
+		{name: "Duplicate", input: "switch (arg) { case 1, 2: return 1; case 2: return 2; } return 0;", error: "Compute error: duplicate case 2"},
+		{name: "Tag", input: "switch (1.5) { case 1: return 1; } return 0;", error: "Compute error: expected int, got flt"},
+		{name: "TwoDefaults", input: "switch (arg) { default: return 1; default: return 2; } return 0;", error: "Syntax error: multiple defaults in switch"},
+		{name: "MissingColon", input: "switch (arg) { case 1 return 1; } return 0;", error: "Syntax error: expected :"},
+		{name: "MissingCase", input: "switch (arg) { return 1; } return 0;", error: "Syntax error: expected case, default or }"},
+		{name: "Continue", input: "switch (arg) { case 1: continue; } return 0;", error: "Syntax error: continue outside of a loop"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestCondExpr() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Select", input: "return arg ? 1 : 2;", output: "return Phi(Region10,1,2);"},
+		{name: "Constant", input: "return 1 ? arg : 2;", output: "return arg;"},
+		{name: "Nested", input: "return arg < 0 ? -1 : arg > 0 ? 1 : 0;", output: "return Phi(Region23,-1,Phi(Region21,1,0));"},
+		{name: "Widen", input: "return arg ? 1 : 2.5;", output: "return Phi(Region12,1.0,2.5);"},
+		{name: "Precedence", input: "int a = arg || 0 ? 3 : 4; return a + 1;", output: "return (Phi(Region23,3,4)+1);"},
+		{name: "Lazy", input: "int[] a = new int[1]; return arg < 1 ? a[arg] : 0;", output: "return Phi(Region21,new int[][arg],0);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidCondExpr() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "MissingColon", input: "return arg ? 1;", error: "Syntax error: expected : in conditional expression"},
+		{name: "Mismatch", input: "return arg ? 1 : true;", error: "Compute error: expected int, got bool"},
+		{name: "Condition", input: "return 1.5 ? 1 : 2;", error: "Compute error: expected bool or int, got flt"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestSizedInts() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "WrapSigned", input: "i8 x = 200; return x;", output: "return -56;"},
+		{name: "WrapUnsigned", input: "u8 x = -1; return x;", output: "return 255;"},
+		{name: "Wrap16", input: "i16 x = 40000; return x;", output: "return -25536;"},
+		{name: "Wrap32", input: "u32 x = -1; return x;", output: "return 4294967295;"},
+		{name: "I64", input: "i64 x = arg; return x;", output: "return arg;"},
+		{name: "Extend", input: "i32 x = arg; return x;", output: "return i32(arg);"},
+		{name: "Increment", input: "i8 x = 127; x++; return x;", output: "return -128;"},
+		{name: "CompoundAssign", input: "u8 x = 255; x += 2; return x;", output: "return 1;"},
+		{name: "Arithmetic", input: "i8 x = arg; x = x + 1; return x;", output: "return i8((i8(arg)+1));"},
+		{name: "SameWidth", input: "i8 x = arg; i8 y = x; return y;", output: "return i8(arg);"},
+		{name: "Wider", input: "i8 x = arg; i16 y = x; return y;", output: "return i8(arg);"},
+		{name: "UnsignedToWider", input: "u8 x = arg; i16 y = x; return y;", output: "return u8(arg);"},
+		{name: "Narrower", input: "i16 x = arg; i8 y = x; return y;", output: "return i8(arg);"},
+		{name: "NarrowerAndBack", input: "i32 x = arg; i8 y = x; i32 z = y; return z;", output: "return i8(arg);"},
+		{name: "ChangeSign", input: "u8 x = arg; i8 y = x; return y;", output: "return i8(arg);"},
+		{name: "Mask", input: "u8 x = arg; return x & 255;", output: "return u8(arg);"},
+		{name: "MaskFits", input: "u16 x = arg & 255; return x;", output: "return (arg&255);"},
+		{name: "Conversion", input: "return i8(300);", output: "return 44;"},
+		{name: "ConversionUnsigned", input: "return u16(-1);", output: "return 65535;"},
+		{name: "ConversionFlt", input: "return u8(1.5e3);", output: "return 220;"},
+		{name: "Loop", input: "i8 x = 0; while (arg < 10) { arg++; x = arg; } return x;", output: "return Phi(Loop7,0,i8((Phi(Loop7,arg,(Phi_arg+1))+1)));"},
+		{name: "Field", input: "struct S { u8 b; } S s = new S; s.b = arg; u8 c = s.b; return c;", output: "return u8(arg);"},
+		{name: "Param", input: "i8 f(i8 a) { return a; } return f(300);", output: "return f(44);"},
+		{name: "I8Max", input: "i8 x = 127; return x + 1;", output: "return -128;"},
+		{name: "I8Min", input: "i8 x = -128; return x - 1;", output: "return 127;"},
+		{name: "I8Negate", input: "i8 x = -128; return -x;", output: "return -128;"},
+		{name: "I8Mul", input: "i8 x = 64; return x * 2;", output: "return -128;"},
+		{name: "I8Div", input: "i8 x = -128; return x / -1;", output: "return -128;"},
+		{name: "U8Max", input: "u8 x = 255; return x + 1;", output: "return 0;"},
+		{name: "U8Min", input: "u8 x = 0; return x - 1;", output: "return 255;"},
+		{name: "U8Not", input: "u8 x = 0; return ^x;", output: "return 255;"},
+		{name: "U8Shift", input: "u8 x = 1; return x << 8;", output: "return 0;"},
+		{name: "I16Max", input: "i16 x = 32767; return x + 1;", output: "return -32768;"},
+		{name: "I16Min", input: "i16 x = -32768; return x - 1;", output: "return 32767;"},
+		{name: "U16Max", input: "u16 x = 65535; return x + 1;", output: "return 0;"},
+		{name: "U16Min", input: "u16 x = 0; return x - 1;", output: "return 65535;"},
+		{name: "I32Max", input: "i32 x = 2147483647; return x + 1;", output: "return -2147483648;"},
+		{name: "I32Min", input: "i32 x = -2147483648; return x - 1;", output: "return 2147483647;"},
+		{name: "U32Max", input: "u32 x = 4294967295; return x + 1;", output: "return 0;"},
+		{name: "U32Min", input: "u32 x = 0; return x - 1;", output: "return 4294967295;"},
+		{name: "WrapUnknown", input: "i8 x = arg; return x + 1;", output: "return i8((i8(arg)+1));"},
+		{name: "WrapField", input: "struct S { u8 b; } S s = new S; s.b = 255; return s.b + 1;", output: "return 0;"},
+		{name: "WrapString", input: `return "\xff"[0] + 1;`, output: "return 0;"},
+		{name: "WrapCompare", input: "u8 x = 255; return x + 1 == 0;", output: "return true;"},
+		{name: "PromoteInt", input: "i8 x = 127; int y = 1; return x + y;", output: "return 128;"},
+		{name: "PromoteMixed", input: "i8 x = 127; u8 y = 1; return x + y;", output: "return 128;"},
+		{name: "LiteralFits", input: "i16 x = arg; return x + 32767;", output: "return i16((i16(arg)+32767));"},
+		{name: "ShiftAmount", input: "u8 x = arg; return x >> 300;", output: "return u8((u8(arg)>>300));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidSizedInts() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Flt", input: "i8 x = 1.5; return x;", error: "Compute error: expected int, got flt"},
+		{name: "Keyword", input: "int u8 = 1; return u8;", error: "Syntax error: u8 is a reserved keyword"},
+		{name: "Uninitialized", input: "u8 x; return x;", error: "Compute error: variable may be used before assignment: x"},
+		{name: "ConversionBool", input: "return i8(true);", error: "Compute error: expected int or flt, got bool"},
+		{name: "LiteralOverflow", input: "i16 x = arg; int y = x + 100000; return y;", error: "1:26: Compute error: constant 100000 overflows i16"},
+		{name: "LiteralOverflowLhs", input: "u8 x = arg; return 256 * x;", error: "1:20: Compute error: constant 256 overflows u8"},
+		{name: "NegativeUnsigned", input: "u8 x = arg; return x + -1;", error: "1:24: Compute error: constant -1 overflows u8"},
+		{name: "ConstantExprOverflow", input: "i8 x = arg; return x + (100 + 100);", error: "1:24: Compute error: constant 200 overflows i8"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestNullable() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Null", input: "struct S { int x; } S? p = null; return p == null;", output: "return true;"},
+		{name: "NonNull", input: "struct S { int x; } S p = new S; return p == null;", output: "return false;"},
+		{name: "NullDeadBranch", input: "struct S { int x; } S? p = null; if (p) return p.x; return 1;", output: "return 1;"},
+		{name: "NonNullCheck", input: "struct S { int x; } S? p = new S; p.x = 0; if (p) return p.x + 2; return 1;", output: "return 2;"},
+		{name: "If", input: "struct S { int x; } S? p = null; if (arg) p = new S; if (p) return p.x; return 1;", output: "return Phi(Region22,(*S)Phi(Region13,new S,null).x,1);"},
+		{name: "Not", input: "struct S { int x; } S? p = null; if (arg) p = new S; if (!p) return 0; return p.x;", output: "return Phi(Region23,0,(*S)Phi(Region13,new S,null).x);"},
+		{name: "NotEqual", input: "struct S { int x; } S? p = null; if (arg) p = new S; if (null != p) return p.x; return 1;", output: "return Phi(Region25,(*S)Phi(Region13,new S,null).x,1);"},
+		{name: "Val", input: "struct S { int x; } S? p = null; if (arg) p = new S; val q = p; if (q) return q.x; return 0;", output: "return Phi(Region22,(*S)Phi(Region13,new S,null).x,0);"},
+		{name: "Merge", input: "struct S { int x; } S? p = null; if (arg) p = new S; if (p) p.x = 1; return p == null;", output: "return (Phi(Region13,new S,null)==null);"},
+		{name: "While", input: "struct S { int x; } S? p = null; if (arg) p = new S; while (p == null) p = new S; return p.x;", output: "return (*S)Phi(Loop16,Phi(Region13,new S,null),new S).x;"},
+		{name: "Array", input: "int[]? a = null; if (arg) a = new int[3]; if (a) return a#; return 0;", output: "return Phi(Region26,(*int[])Phi(Region16,new int[],null)#,0);"},
+		{name: "Widen", input: "struct S { int x; } S p = new S; S? q = p; return q.x;", output: "return 0;"},
+		{name: "AndNotNull", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p != null && p.x;", output: "return Phi(Region29,(!((*S)Phi(Region13,new S,null).x==0)),false);"},
+		{name: "And", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p && p.x;", output: "return Phi(Region26,(!((*S)Phi(Region13,new S,null).x==0)),false);"},
+		{name: "OrNull", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p == null || p.x;", output: "return Phi(Region28,(!((*S)Phi(Region13,new S,null).x==0)),true);"},
+		{name: "OrNot", input: "struct S { int x; } S? p = null; if (arg) p = new S; return !p || p.x;", output: "return Phi(Region27,(!((*S)Phi(Region13,new S,null).x==0)),true);"},
+		{name: "Cond", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p ? p.x : 0;", output: "return Phi(Region23,(*S)Phi(Region13,new S,null).x,0);"},
+		{name: "CondNull", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p == null ? 0 : p.x;", output: "return Phi(Region25,0,(*S)Phi(Region13,new S,null).x);"},
+		{name: "AndChain", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p && p.x == 1 && p.x;", output: "return Phi(Region38,(!((*S)Phi(Region13,new S,null).x==0)),false);"},
+		{name: "IfAnd", input: "struct S { int x; } S? p = null; S? q = null; if (arg) { p = new S; q = new S; } if (p && q) return p.x + q.x; return 0;", output: "return Phi(Region42,((*S)Phi(Region17,new S,null).x+(*S)Phi(Region17,new S,null).x),0);"},
+		{name: "IfOr", input: "struct S { int x; } S? p = null; S? q = null; if (arg) { p = new S; q = new S; } if (p == null || q == null) return 0; return p.x + q.x;", output: "return Phi(Region41,0,((*S)Phi(Region17,new S,null).x+(*S)Phi(Region17,new S,null).x));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidNullable() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Field", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "Length", input: "int[]? a = null; if (arg) a = new int[3]; return a#;", error: "Compute error: cannot get the length of possibly null int[]?"},
+		{name: "Index", input: "int[]? a = null; if (arg) a = new int[3]; return a[0];", error: "Compute error: cannot index possibly null int[]?"},
+		{name: "AssignNull", input: "struct S { int x; } S p = null; return 0;", error: "Compute error: expected S, got null"},
+		{name: "AssignNullable", input: "struct S { int x; } S? q = null; if (arg) q = new S; S p = q; return 0;", error: "Compute error: expected S, got S?"},
+		{name: "Infer", input: "var p = null; return 0;", error: "Compute error: cannot infer the type of null"},
+		{name: "Primitive", input: "int? x = 1; return x;", error: "Compute error: only references can be nullable, got int"},
+		{name: "Keyword", input: "int null = 1; return null;", error: "Syntax error: null is a reserved keyword"},
+		{name: "AfterBranch", input: "struct S { int x; } S? p = null; if (arg) p = new S; if (p) p.x = 1; return p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "OrNotNull", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p || p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "CondElse", input: "struct S { int x; } S? p = null; if (arg) p = new S; return p ? 0 : p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "AfterAnd", input: "struct S { int x; } S? p = null; if (arg) p = new S; bool b = p && p.x; return p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "IfAndElse", input: "struct S { int x; } S? p = null; S? q = null; if (arg) { p = new S; q = new S; } if (p && q) return 0; return p.x;", error: "Compute error: cannot access field x of possibly null S?"},
+		{name: "CompareStructs", input: "struct S { int x; } struct T { int x; } S? s = null; T? t = null; return s == t;", error: "1:79: Compute error: expected S?, got T?"},
+		{name: "CompareStructsPhi", input: "struct S { int x; } struct T { int x; } S? s = null; if (arg) s = new S; T? t = null; return s == t;", error: "Compute error: expected S?, got T?"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestStrings() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Length", input: `return "abc"#;`, output: "return 3;"},
+		{name: "Escapes", input: `return "a\tb\n\x41\\\""#;`, output: "return 7;"},
+		{name: "UTF8", input: `return "é"#;`, output: "return 2;"},
+		{name: "Index", input: `return "abc"[1];`, output: "return 98;"},
+		{name: "HexEscape", input: `return "\xff"[0];`, output: "return 255;"},
+		{name: "Equal", input: `return "abc" == "abc";`, output: "return true;"},
+		{name: "NotEqual", input: `return "abc" != "abd";`, output: "return true;"},
+		{name: "Empty", input: `return "" == "";`, output: "return true;"},
+		{name: "Var", input: `u8[] s = "hello"; return s# + s[4];`, output: "return 116;"},
+		{name: "SameLiteral", input: `var s = "hi"; if (arg) s = "hi"; return s#;`, output: "return 2;"},
+		{name: "Phi", input: `var s = "hi"; if (arg) s = "hello"; return s#;`, output: `return Phi(Region12,"hello","hi")#;`},
+		{name: "Array", input: `u8[] s = "hi"; if (arg) s = new u8[3]; return s == "hi";`, output: `return (Phi(Region16,new u8[],"hi")=="hi");`},
+		{name: "NotNull", input: `u8[] s = "hi"; return s == null;`, output: "return false;"},
+		{name: "UnknownIndex", input: `return "abc"[arg];`, output: `return "abc"[arg];`},
+		{name: "Bytes", input: `u8[] s = new u8[2]; s[0] = 300; return s[0];`, output: "return 44;"},
+		{name: "Copy", input: `int[] a = new int[2]; u8[] s = "x"; a[0] = s[0]; return a[0];`, output: "return 120;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidStrings() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "NotTerminated", input: `return "abc;`, error: "Syntax error: string literal not terminated"},
+		{name: "Escape", input: `return "a\qb"#;`, error: "Syntax error: invalid escape sequence"},
+		{name: "OutOfBounds", input: `return "abc"[3];`, error: "Compute error: index 3 out of bounds for length 3"},
+		{name: "Modify", input: `u8[] s = "abc"; s[0] = 1; return 0;`, error: "Compute error: cannot modify a string literal"},
+		{name: "IntArray", input: `int[] a = "abc"; return 0;`, error: "Compute error: expected int[], got u8[]"},
+		{name: "ElementType", input: "flt[] a = new flt[1]; return 0;", error: "Compute error: arrays must be of int or u8"},
+		{name: "Return", input: `return "abc";`, error: "Compute error: expected int, flt or bool, got u8[]"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+// modules resolves import paths to the sources of a map, in files named after the path.
+type modules map[string]string
+
+func (m modules) Resolve(path string) (string, string, error) {
+	source, ok := m[path]
+	if !ok {
+		return "", "", errors.New("module not found")
+	}
+	return path + simple.ModuleExt, source, nil
+}
+
+func (suite *SimpleTestSuite) TestModules() {
+	subTests := []struct {
+		name    string
+		input   string
+		modules modules
+		output  string
+	}{
+		{name: "Function", input: `import "math"; return sq(arg);`, modules: modules{"math": "export int sq(int x) { return x*x; }"}, output: "return sq(arg);"},
+		{name: "Struct", input: `import "geo"; Point p = new Point; p.x = 2; return p.x;`, modules: modules{"geo": "export struct Point { int x; int y; }"}, output: "return 2;"},
+		{name: "Private", input: `import "math"; return sq(arg);`, modules: modules{"math": "int mul(int a, int b) { return a*b; } export int sq(int x) { return mul(x, x); }"}, output: "return sq(arg);"},
+		{name: "ImportedStruct", input: `import "shapes"; return area(2);`, modules: modules{"geo": "export struct Point { int x; int y; }", "shapes": `import "geo"; export int area(int n) { Point p = new Point; p.x = n; return p.x*p.x; }`}, output: "return area(2);"},
+		{name: "Diamond", input: `import "a"; import "b"; return fa(1) + fb(2);`, modules: modules{"a": `import "c"; export int fa(int x) { return fc(x); }`, "b": `import "c"; export int fb(int x) { return fc(x); }`, "c": "export int fc(int x) { return x+1; }"}, output: "return (fb(2)+fa(1));"},
+		{name: "SameName", input: `import "a"; int f() { return 1; } return f() + g();`, modules: modules{"a": "int f() { return 2; } export int g() { return f(); }"}, output: "return 3;"},
+		{name: "Nested", input: `import "geo/vec"; return dot(arg, 3);`, modules: modules{"geo/vec": "export int dot(int a, int b) { return a*b; }"}, output: "return dot(arg,3);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.SimpleFile("main.smp", test.input, test.modules, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidModules() {
+	subTests := []struct {
+		name    string
+		input   string
+		modules modules
+		error   string
+	}{
+		{name: "Unexported", input: `import "math"; return mul(1, 2);`, modules: modules{"math": "int mul(int a, int b) { return a*b; }"}, error: "main.smp:1:23: Compute error: unknown function"},
+		{name: "Transitive", input: `import "a"; return fb();`, modules: modules{"a": `import "b"; export int fa() { return fb(); }`, "b": "export int fb() { return 1; }"}, error: "main.smp:1:20: Compute error: unknown function"},
+		{name: "Cycle", input: `import "a"; return 1;`, modules: modules{"a": `import "b";`, "b": `import "a";`}, error: `b.smp:1:8: Import error: import cycle: a -> b -> a`},
+		{name: "SelfImport", input: `import "a"; return 1;`, modules: modules{"a": `import "a";`}, error: `a.smp:1:8: Import error: import cycle: a -> a`},
+		{name: "NotFound", input: `import "math"; return 1;`, modules: modules{}, error: `main.smp:1:8: Import error: cannot import "math": module not found`},
+		{name: "InvalidPath", input: `import "../math"; return 1;`, modules: modules{}, error: `main.smp:1:8: Import error: invalid import path "../math"`},
+		{name: "SyntaxError", input: `import "math"; return 1;`, modules: modules{"math": "export int sq(int x) { return x*; }"}, error: "math.smp:1:33: Syntax error: expected identifier"},
+		{name: "ComputeError", input: `import "math"; return sq(1);`, modules: modules{"math": "export int sq(int x) { return y; }"}, error: "math.smp:1:31: Compute error: unknown identifier"},
+		{name: "Statement", input: `import "math"; return 1;`, modules: modules{"math": "int x = 1;"}, error: "math.smp:1:1: Compute error: module math can only declare functions and structs"},
+		{name: "Conflict", input: `import "a"; import "b"; return f();`, modules: modules{"a": "export int f() { return 1; }", "b": "export int f() { return 2; }"}, error: `main.smp:1:20: Compute error: cannot import f from "b": name already defined`},
+		{name: "Redefined", input: `import "a"; int f() { return 1; } return f();`, modules: modules{"a": "export int f() { return 2; }"}, error: "main.smp:1:17: Compute error: function already defined"},
+		{name: "RedefinedByImport", input: `int f() { return 1; } import "a"; return f();`, modules: modules{"a": "export int f() { return 2; }"}, error: `main.smp:1:30: Compute error: cannot import f from "a": name already defined`},
+		{name: "StructRedefinedByImport", input: `struct P { int x; } import "geo"; return 0;`, modules: modules{"geo": "export struct P { int y; }"}, error: `main.smp:1:28: Compute error: cannot import P from "geo": name already defined`},
+		{name: "Nested", input: `{ import "a"; } return 1;`, modules: modules{}, error: "main.smp:1:3: Syntax error: imports can only be declared at the top level"},
+		{name: "ExportStatement", input: "export int x = 1; return x;", modules: modules{}, error: "main.smp:1:1: Syntax error: only functions and structs can be exported"},
+		{name: "NoResolver", input: `import "a"; return 1;`, error: `1:8: Import error: cannot import "a" without a module resolver`},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			var resolver simple.Resolver
+			if test.modules != nil {
+				resolver = test.modules
+			}
+			ret, _, err := simple.SimpleFile("main.smp", test.input, resolver, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestDirResolver() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.Mkdir(filepath.Join(dir, "geo"), 0o755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "geo", "vec.smp"), []byte("export int dot(int a, int b) { return a*b+c; }"), 0o644))
+
+	_, _, err := simple.SimpleFile("main.smp", `import "geo/vec"; return dot(arg, 2);`, simple.DirResolver{Dir: dir}, nil)
+	suite.ErrorContains(err, filepath.Join(dir, "geo", "vec.smp")+":1:43: Compute error: unknown identifier")
+
+	_, _, err = simple.SimpleFile("main.smp", `import "geo/mat"; return 1;`, simple.DirResolver{Dir: dir}, nil)
+	suite.ErrorContains(err, `main.smp:1:8: Import error: cannot import "geo/mat": `)
+}
+
+func (suite *SimpleTestSuite) TestFunctions() {
+	subTests := []struct {
+		name      string
+		input     string
+		output    string
+		functions []string
+	}{
+		{name: "Square", input: "int sq(int x) { return x*x; } return sq(arg)+1;", output: "return (sq(arg)+1);", functions: []string{"return (x*x);"}},
+		{name: "MultipleParams", input: "int add(int a, int b) { return a+b; } return add(arg, 2);", output: "return add(arg,2);", functions: []string{"return (b+a);"}},
+		{name: "Constant", input: "int one() { return 1; } return one()+1;", output: "return 2;", functions: []string{"return 1;"}},
+		{name: "Recursive", input: "int fact(int n) { if (n <= 1) return 1; return n * fact(n-1); } return fact(arg);", output: "return fact(arg);", functions: []string{"return Phi(Region15,1,(n*fact((n-1))));"}},
+		{name: "DeclaredAfterUse", input: "return twice(arg); int twice(int x) { return x*2; }", output: "return twice(arg);", functions: []string{"return (x*2);"}},
+		{name: "Loop", input: "int sum(int n) { int s=0; while(n) { s=s+n; n=n-1; } return s; } return sum(3);", output: "return sum(3);", functions: []string{"return Phi(Loop9,0,(Phi_s+Phi(Loop9,n,(Phi_n-1))));"}},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, generator, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+			suite.Require().Len(generator.Functions, len(test.functions))
+			for i, fun := range generator.Functions {
+				suite.Equal(test.functions[i], ir.ToString(fun.Ret))
+			}
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidFunctions() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Nested", input: "{ int f() { return 1; } } return 1;", error: "Syntax error: functions can only be declared at the top level"},
+		{name: "InLoop", input: "while(arg) int f() { return 1; } return 1;", error: "Syntax error: functions can only be declared at the top level"},
+		{name: "ParamType", input: "int f(a) { return 1; } return 1;", error: "Syntax error: expected parameter type int"},
+		{name: "ParamList", input: "int f(int a int b) { return 1; } return 1;", error: "Syntax error: expected , or ) after parameter"},
+		{name: "Body", input: "int f(int a) return a; return 1;", error: "Syntax error: expected { before function body"},
+		{name: "Args", input: "int f(int a) { return a; } return f(1 2);", error: "Syntax error: expected , or ) after argument"},
+		{name: "MissingReturn", input: "int f(int a) { a = 2; } return 1;", error: "Compute error: missing return in function f"},
+		{name: "ArgCount", input: "int f(int a) { return a; } return f(1,2);", error: "Compute error: f expects 1 arguments, got 2"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestStructs() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "ZeroInit", input: "struct P { int x; int y; } P p = new P; return p.y;", output: "return 0;"},
+		{name: "StoreLoad", input: "struct P { int x; int y; } P p = new P; p.x = arg; p.y = 2; return p.x+p.y;", output: "return (arg+2);"},
+		{name: "Aliased", input: "struct P { int x; } P p = new P; P q = p; q.x = 3; return p.x;", output: "return 3;"},
+		{name: "DistinctAllocations", input: "struct P { int x; } P p = new P; P q = new P; p.x = 1; q.x = 2; return p.x;", output: "return 1;"},
+		{name: "DistinctStructs", input: "struct P { int x; } struct Q { int x; } P p = new P; Q q = new Q; p.x = 1; q.x = 2; return p.x;", output: "return 1;"},
+		{name: "Merge", input: "struct P { int x; } P p = new P; if (arg) p.x = 1; else p.x = 2; return p.x;", output: "return Phi(Region16,1,2);"},
+		{name: "Reassigned", input: "struct P { int x; } P p = new P; if (arg) p = new P; p.x = 5; return p.x;", output: "return 5;"},
+		{name: "Loop", input: "struct P { int x; } P p = new P; while (arg < 10) { p.x = p.x + 1; arg = arg + 1; } return p.x;", output: "return new P.x;"},
+		{name: "Equal", input: "struct P { int x; } P p = new P; return p == p;", output: "return true;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidStructs() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Nested", input: "{ struct P { int x; } } return 1;", error: "Syntax error: structs can only be declared at the top level"},
+		{name: "MissingSemicolon", input: "struct P { int x } return 1;", error: "Syntax error: expected ; after field"},
+		{name: "MissingCurly", input: "struct P int x; return 1;", error: "Syntax error: expected { after struct name"},
+		{name: "FieldType", input: "struct P { P x; } return 1;", error: "Compute error: fields must be int"},
+		{name: "UnknownType", input: "Q q = new Q; return 1;", error: "Compute error: unknown type Q"},
+		{name: "UnknownField", input: "struct P { int x; } P p = new P; return p.y;", error: "Compute error: unknown field y in P"},
+		{name: "NotAStruct", input: "return arg.x;", error: "Compute error: expected a struct, got int"},
+		{name: "ReturnStruct", input: "struct P { int x; } P p = new P; return p;", error: "Compute error: expected int, flt or bool, got P"},
+		{name: "AssignInt", input: "struct P { int x; } P p = new P; p = 1; return 1;", error: "Compute error: expected P, got int"},
+		{name: "StructArg", input: "struct P { int x; } int f(int a) { return a; } return f(new P);", error: "Compute error: expected int, got P"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestArrays() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Length", input: "int[] a = new int[arg]; return a#;", output: "return arg;"},
+		{name: "ZeroInit", input: "int[] a = new int[3]; a[1] = 5; return a[2];", output: "return 0;"},
+		{name: "StoreLoad", input: "int[] a = new int[3]; a[1] = 5; a[2] = 7; return a[1] + a[2];", output: "return 12;"},
+		{name: "UnknownIndex", input: "int[] a = new int[3]; a[arg] = 5; return a[1];", output: "return new int[][1];"},
+		{name: "DeadIndex", input: "int[] a = new int[3]; if (0) return a[5]; return a[2];", output: "return 0;"},
+		{name: "Merge", input: "int[] a = new int[3]; if (arg) a[0] = 1; else a[0] = 2; return a[0];", output: "return Phi(Region27,1,2);"},
+		{name: "Loop", input: "int[] a = new int[10]; int i = 0; while (i < a#) { a[i] = i; i = i + 1; } return a[3];", output: "return new int[][3];"},
+		{name: "Expression", input: "int[] a = new int[arg+1]; return a# * 2;", output: "return ((arg+1)*2);"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidArrays() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "TypeBracket", input: "int[ a = new int[3]; return 1;", error: "Syntax error: expected ]"},
+		{name: "LengthBracket", input: "int[] a = new int[3; return 1;", error: "Syntax error: expected ]"},
+		{name: "IndexBracket", input: "int[] a = new int[3]; return a[1;", error: "Syntax error: expected ]"},
+		{name: "StructArray", input: "struct P { int x; } return new P[2]#;", error: "Compute error: arrays must be of int"},
+		{name: "NotAnArray", input: "return arg[0];", error: "Compute error: expected an array, got int"},
+		{name: "NegativeLength", input: "int[] a = new int[-1]; return a#;", error: "1:19: Compute error: negative array length -1"},
+		{name: "OutOfBounds", input: "int[] a = new int[3]; return a[5];", error: "1:32: Compute error: index 5 out of bounds for length 3"},
+		{name: "StoreOutOfBounds", input: "int[] a = new int[3]; a[3] = 1; return 0;", error: "1:25: Compute error: index 3 out of bounds for length 3"},
+		{name: "NegativeIndex", input: "int[] a = new int[arg]; return a[-1];", error: "1:34: Compute error: negative index -1"},
+		{name: "AssignLength", input: "int[] a = new int[3]; a# = 2; return 1;", error: "Compute error: cannot assign to the length of an array"},
+		{name: "ReturnArray", input: "int[] a = new int[3]; return a;", error: "Compute error: expected int, flt or bool, got int[]"},
+		{name: "UnknownField", input: "int[] a = new int[3]; return a.x;", error: "Compute error: unknown field x in int[]"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestFloats() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Literal", input: "return 1.5;", output: "return 1.5;"},
+		{name: "Exponent", input: "return 2e10;", output: "return 2e+10;"},
+		{name: "NegativeExponent", input: "return 2.5e-3;", output: "return 0.0025;"},
+		{name: "Widen", input: "flt f = 1; return f / 4;", output: "return 0.25;"},
+		{name: "Arg", input: "flt f = arg; return f * 1;", output: "return flt(arg);"},
+		{name: "Negate", input: "return -0.0;", output: "return -0.0;"},
+		{name: "NaN", input: "return 0.0/0.0 != 0.0/0.0;", output: "return true;"},
+		{name: "Conversion", input: "return int(2.9) + int(flt(arg));", output: "return (int(flt(arg))+2);"},
+		{name: "Loop", input: "flt f = 1; while (f < 10) f = f * 2; return f;", output: "return Phi(Loop7,1.0,(Phi_f*2.0));"},
+		{name: "Function", input: "flt half(flt x) { return x / 2; } return half(arg);", output: "return half(flt(arg));"},
+		{name: "Field", input: "struct P { flt x; int y; } P p = new P; p.y = 3; return p.x;", output: "return 0.0;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidFloats() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "LeadingZero", input: "return 01.5;", error: "Syntax error: integer values cannot start with '0'"},
+		{name: "MissingFraction", input: "return 1.;", error: "Syntax error: expected ;"},
+		{name: "MissingExponent", input: "return 1e;", error: "Syntax error: expected ;"},
+		{name: "ParamType", input: "int f(str a) { return 1; } return 1;", error: "Syntax error: expected parameter type int, flt or bool"},
+		{name: "Narrow", input: "int i = 1.5; return i;", error: "Compute error: expected int, got flt"},
+		{name: "FltParam", input: "int f(int a) { return a; } return f(1.5);", error: "Compute error: expected int, got flt"},
+		{name: "OutOfRange", input: "return 1e400;", error: "Compute error: invalid float 1e400"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestBools() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Literal", input: "return false;", output: "return false;"},
+		{name: "Compare", input: "return 1 < 2;", output: "return true;"},
+		{name: "Decl", input: "bool b = arg == 1; return !b;", output: "return (!(arg==1));"},
+		{name: "Condition", input: "bool b = true; int a = 1; if (b) a = 2; return a;", output: "return 2;"},
+		{name: "Loop", input: "bool done = false; int i = 0; while (!done) { i = i + 1; done = i == 10; } return i;", output: "return Phi(Loop6,0,(Phi_i+1));"},
+		{name: "Function", input: "bool even(int x) { return x / 2 * 2 == x; } return even(arg);", output: "return even(arg);"},
+		{name: "Field", input: "struct P { bool b; } P p = new P; return p.b;", output: "return false;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidBools() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Mixed", input: "return (1 < 2) + 3;", error: "Compute error: expected int or flt, got bool"},
+		{name: "MixedEqual", input: "return true == 1;", error: "Compute error: expected bool, got int"},
+		{name: "Assign", input: "bool b = true; b = 1; return b;", error: "Compute error: expected bool, got int"},
+		{name: "Negate", input: "return -true;", error: "Compute error: expected int or flt, got bool"},
+		{name: "Return", input: "int f() { return true; } return f();", error: "Compute error: expected int, got bool"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.Contains(err.Error(), test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestLogical() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Range", input: "return arg > 1 && arg < 10;", output: "return Phi(Region13,(arg<10),false);"},
+		{name: "Or", input: "return arg == 1 || arg == 2;", output: "return Phi(Region13,(arg==2),true);"},
+		{name: "ShortCircuit", input: "int f(int x) { return x; } return 0 && f(arg);", output: "return false;"},
+		{name: "ConstantLhs", input: "return true || arg;", output: "return true;"},
+		{name: "Precedence", input: "return 1 < 2 && 2 + 3 < 4;", output: "return false;"},
+		{name: "Loop", input: "int i = 0; while (i < 10 && arg) i = i + 1; return i;", output: "return Phi(Loop5,0,(Phi_i+1));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestBitwise() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Mask", input: "return arg & 255;", output: "return (arg&255);"},
+		{name: "Flags", input: "return 1 << 3 | 1 << 1;", output: "return 10;"},
+		{name: "Unsigned", input: "return -16 >>> 62;", output: "return 3;"},
+		{name: "Signed", input: "return -16 >> 2;", output: "return -4;"},
+		{name: "Xor", input: "int x = arg ^ 5; return x ^ x;", output: "return 0;"},
+		{name: "Precedence", input: "return 1 + 2 << 3;", output: "return 17;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestAssignOps() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Add", input: "int a = 1; a += arg; return a;", output: "return (arg+1);"},
+		{name: "All", input: "int a = 10; a -= 2; a *= 3; a /= 4; return a;", output: "return 6;"},
+		{name: "Increment", input: "int a = arg; a++; a++; return a;", output: "return (arg+2);"},
+		{name: "Decrement", input: "int a = 3; a--; return a;", output: "return 2;"},
+		{name: "Float", input: "flt f = 1; f += 0.5; return f;", output: "return 1.5;"},
+		{name: "Field", input: "struct P { int x; } P p = new P; p.x += 2; p.x++; return p.x;", output: "return 3;"},
+		{name: "Element", input: "int[] a = new int[2]; a[1] += 4; a[1]--; return a[1];", output: "return 3;"},
+		{name: "Loop", input: "int i = 0; while (i < 10) i++; return i;", output: "return Phi(Loop5,0,(Phi_i+1));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestVarVal() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Int", input: "var a = arg; a = a + 1; return a;", output: "return (arg+1);"},
+		{name: "Float", input: "var f = 1.5; f = f * 2; return f;", output: "return 3.0;"},
+		{name: "Bool", input: "var b = arg < 3; b = !b; return b;", output: "return (!(arg<3));"},
+		{name: "Struct", input: "struct P { int x; } var p = new P; p.x = 2; return p.x;", output: "return 2;"},
+		{name: "Val", input: "val a = arg * 2; return a + 1;", output: "return ((arg*2)+1);"},
+		{name: "ValField", input: "struct P { int x; } val p = new P; p.x = 2; return p.x;", output: "return 2;"},
+		{name: "ValInBlock", input: "int a = 1; { val b = 2; a = b; } return a;", output: "return 2;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidVarVal() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "AssignVal", input: "val a = 1; a = 2; return a;", error: "1:12: Compute error: cannot assign to val a"},
+		{name: "CompoundVal", input: "val a = 1; a += 2; return a;", error: "1:12: Compute error: cannot assign to val a"},
+		{name: "IncrementVal", input: "val a = 1;\na++;\nreturn a;", error: "2:1: Compute error: cannot assign to val a"},
+		{name: "InferredInt", input: "var a = 1; a = 1.5; return a;", error: "Compute error: expected int, got flt"},
+		{name: "InferredBool", input: "var b = true; b = 1; return b;", error: "Compute error: expected bool, got int"},
+		{name: "CompoundBool", input: "var b = true; b += 1; return b;", error: "Compute error: expected int or flt, got bool"},
+		{name: "MissingValue", input: "var a; return a;", error: "Syntax error: expected ="},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestUninitialized() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Assigned", input: "int a; a = arg; return a;", output: "return arg;"},
+		{name: "BothBranches", input: "int a; if (arg) a = 1; else a = 2; return a;", output: "return Phi(Region11,1,2);"},
+		{name: "ReturnedBranch", input: "flt f; if (arg) f = 1.5; else return 0; return f;", output: "return Phi(Region11,0,1.5);"},
+		{name: "InLoop", input: "int a = 0; while (a < arg) { int b; b = a; a = b + 1; } return a;", output: "return Phi(Loop5,0,(Phi_a+1));"},
+		{name: "Struct", input: "struct P { int x; } P p; p = new P; p.x = 3; return p.x;", output: "return 3;"},
+		{name: "Array", input: "int[] a; if (arg) a = new int[1]; else a = new int[2]; return a#;", output: "return Phi(Region21,new int[],new int[])#;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidUninitialized() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "NeverAssigned", input: "int a; return a;", error: "1:15: Compute error: variable may be used before assignment: a"},
+		{name: "OneBranch", input: "int a;\nif (arg) a = 1;\nreturn a;", error: "3:8: Compute error: variable may be used before assignment: a"},
+		{name: "Loop", input: "int a; while (arg) { a = 1; arg = arg - 1; } return a;", error: "Compute error: variable may be used before assignment: a"},
+		{name: "Break", input: "int a; while (arg) { if (arg < 3) break; a = 1; } return a;", error: "Compute error: variable may be used before assignment: a"},
+		{name: "SelfAssign", input: "int a; a = a + 1; return a;", error: "1:12: Compute error: variable may be used before assignment: a"},
+		{name: "Compound", input: "int a; a += 1; return a;", error: "1:8: Compute error: variable may be used before assignment: a"},
+		{name: "Struct", input: "struct P { int x; } P p; return p.x;", error: "Compute error: variable may be used before assignment: p"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestZeroInitialize() {
+	ir.ZeroInitialize = true
+	defer func() { ir.ZeroInitialize = false }()
+
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Int", input: "int a; return a;", output: "return 0;"},
+		{name: "OneBranch", input: "int a; if (arg) a = 1; return a;", output: "return Phi(Region10,1,0);"},
+		{name: "Float", input: "flt f; return f + 1;", output: "return 1.0;"},
+		{name: "Bool", input: "bool b; return !b;", output: "return true;"},
+		{name: "Nullable", input: "struct P { int x; } P? p; return p == null;", output: "return true;"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+
+	// References have no zero value
+	_, _, err := simple.Simple("struct P { int x; } P p; return p.x;", nil)
+	suite.ErrorContains(err, "variable may be used before assignment: p")
+}
+
+func (suite *SimpleTestSuite) TestSymbolErrors() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "KeywordDecl", input: "int return = 1; return 1;", error: "1:5: Syntax error: return is a reserved keyword"},
+		{name: "KeywordVar", input: "var while = 1; return 1;", error: "1:5: Syntax error: while is a reserved keyword"},
+		{name: "KeywordStructDecl", input: "struct P { int x; } P if = new P; return 1;", error: "1:23: Syntax error: if is a reserved keyword"},
+		{name: "KeywordStruct", input: "struct int { int x; } return 1;", error: "1:8: Syntax error: int is a reserved keyword"},
+		{name: "KeywordField", input: "struct P { int new; } return 1;", error: "1:16: Syntax error: new is a reserved keyword"},
+		{name: "KeywordParam", input: "int f(int val) { return 1; } return f(1);", error: "1:11: Syntax error: val is a reserved keyword"},
+		{name: "KeywordFunction", input: "int break() { return 1; } return 1;", error: "1:5: Syntax error: break is a reserved keyword"},
+		{name: "KeywordAssign", input: "else = 1; return 1;", error: "1:1: Syntax error: unexpected keyword else"},
+		{name: "KeywordExpr", input: "return 1 + while;", error: "1:12: Syntax error: unexpected keyword while"},
+		{name: "Redefined", input: "int abc = 1; int abc = 2; return abc;", error: "1:18: Compute error: name already defined: abc"},
+		{name: "RedefinedUninit", input: "int abc = 1;\nflt abc;\nreturn abc;", error: "2:5: Compute error: name already defined: abc"},
+		{name: "RedefinedParam", input: "int f(int a, int a) { return a; } return f(1, 2);", error: "1:18: Compute error: name already defined: a"},
+		{name: "Suggestion", input: "int count = 1; return coutn;", error: "1:23: Compute error: unknown identifier, did you mean `count`?"},
+		{name: "SuggestionAssign", input: "int total = 0; totl = 1; return total;", error: "1:16: Compute error: unknown identifier, did you mean `total`?"},
+		{name: "SuggestionInner", input: "int total = 1; { int totel = 2; return totl; }", error: "Compute error: unknown identifier, did you mean `totel`?"},
+		{name: "SuggestionArg", input: "return ar;", error: "Compute error: unknown identifier, did you mean `arg`?"},
+		{name: "NoSuggestion", input: "int count = 1; return x;", error: "1:23: Compute error: unknown identifier\n"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.Simple(test.input, nil)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestGoSimple() {
+	subTests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{name: "Expression", input: "1 + 2*3", output: "return 7;"},
+		{name: "Comment", input: "// f adds one\nfunc f(arg int) int { return arg + 1 }", output: "return (arg+1);"},
+		{name: "Return", input: "func f(arg int) int { return arg + 1 }", output: "return (arg+1);"},
+		{name: "Define", input: "func f(arg int) int { a := arg * 2; a += 1; return a }", output: "return ((arg*2)+1);"},
+		{name: "Var", input: "func f(arg int) int {\n\tvar a int\n\tvar b = 2\n\ta++\n\treturn a + b\n}", output: "return 3;"},
+		{name: "Const", input: "func f(arg int) int { const c = 3; return arg * c }", output: "return (arg*3);"},
+		{name: "Float", input: "func f(arg int) float64 { var x float64; x = float64(arg) / 2; return x }", output: "return (flt(arg)/2.0);"},
+		{name: "Bool", input: "func f(arg int) bool { return !(arg < 3) }", output: "return (!(arg<3));"},
+		{name: "Param", input: "func f(n int) int { return n + 1 }", output: "return (arg+1);"},
+		{name: "NoParam", input: "func f() int { return 5 }", output: "return 5;"},
+		{name: "ReturnIntAsFloat", input: "func f(arg int) float64 { return arg }", output: "return flt(arg);"},
+		{name: "If", input: "func f(arg int) int { if arg == 1 { return 2 } else if arg == 2 { return 3 }; return 4 }", output: "return Phi(Region11,2,3,4);"},
+		{name: "IfInit", input: "func f(arg int) int { a := 1; if b := arg; b > 0 { a = b }; return a }", output: "return Phi(Region11,arg,1);"},
+		{name: "For", input: "func f(arg int) int { sum := 0; for i := 0; i < arg; i++ { sum += i }; return sum }", output: "return Phi(Loop6,0,(Phi(Loop6,0,(Phi_i+1))+Phi_sum));"},
+		{name: "While", input: "func f(arg int) int { for arg < 10 { arg = arg + 1 }; return arg }", output: "return Phi(Loop4,arg,(Phi_arg+1));"},
+		{name: "Infinite", input: "func f(arg int) int { for { if arg > 5 { break }; arg++ }; return arg }", output: "return Phi(Loop4,arg,(Phi_arg+1));"},
+		{name: "Continue", input: "func f(arg int) int { s := 0; for i := 0; i < 10; i++ { if i == arg { continue }; s++ }; return s }", output: "return Phi(Loop6,0,Phi(Region26,Phi_s,(Phi_s+1)));"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.GoSimple(test.input, nil)
+			suite.Require().NoError(err)
+			suite.Equal(test.output, ir.ToString(ret))
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestInvalidGoSimple() {
+	subTests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{name: "Syntax", input: "func f(arg int) int { return arg + }", error: "1:36: Syntax error: expected operand"},
+		{name: "FuncPrefix", input: "funcX + 1", error: "1:1: Compute error: unknown identifier"},
+		{name: "Unknown", input: "func f(arg int) int {\n\treturn b\n}", error: "2:9: Compute error: unknown identifier"},
+		{name: "Type", input: "func f(arg int) int { var s string; return 1 }", error: "1:29: Syntax error: unsupported type, expected int, float64 or bool"},
+		{name: "Statement", input: "func f(arg int) int { go f(1); return 1 }", error: "1:23: Syntax error: unsupported statement"},
+		{name: "Operator", input: "func f(arg int) int { return arg &^ 1 }", error: "1:34: Syntax error: unsupported operator &^"},
+		{name: "Call", input: "func f(arg int) int { return f(arg) }", error: "1:30: Syntax error: unsupported call"},
+		{name: "Params", input: "func f(a, b int) int { return a }", error: "1:7: Syntax error: expected at most one parameter"},
+		{name: "Result", input: "func f(arg int) { return }", error: "Syntax error: expected a single unnamed result"},
+		{name: "TwoFunctions", input: "func f() int { return 1 }\nfunc g() int { return 2 }", error: "2:1: Syntax error: expected a single function"},
+		{name: "Multiple", input: "func f(arg int) int { a, b := 1, 2; return a }", error: "1:23: Syntax error: expected a single value on each side of :="},
+		{name: "Redefined", input: "func f(arg int) int { a := 1; a := 2; return a }", error: "1:31: Compute error: name already defined: a"},
+		{name: "Const", input: "func f(arg int) int { const c = 1; c = 2; return c }", error: "1:36: Compute error: cannot assign to val c"},
+		{name: "ReturnIntAsBool", input: "func f() bool { return 1 }", error: "1:24: Compute error: expected bool, got int"},
+		{name: "ReturnFltAsInt", input: "func f() int { x := 1.5; return x }", error: "1:33: Compute error: expected int, got flt"},
+		{name: "ReturnBoolAsFloat", input: "func f(arg int) float64 { if arg > 1 { return 1.5 }; return arg > 2 }", error: "1:61: Compute error: expected flt, got bool"},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			ret, _, err := simple.GoSimple(test.input, nil)
+			suite.IsType(&simple.SourceError{}, err)
+			suite.ErrorContains(err, test.error)
+			suite.Nil(ret)
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestErrorColumn() {
+	subTests := []struct {
+		name   string
+		input  string
+		caret  string
+		column int
+	}{
+		{name: "Ascii", input: "return x;", caret: "\n  |        ^", column: 8},
+		{name: "Unicode", input: "int größe = 1; return grö;", caret: "\n  |                       ^", column: 23},
+		{name: "SecondLine", input: "int π = 1;\nreturn ρ;", caret: "\n  |        ^", column: 8},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, _, err := simple.Simple(test.input, nil)
+			var sourceErr *simple.SourceError
+			suite.Require().ErrorAs(err, &sourceErr)
+			suite.Contains(err.Error(), test.caret)
+			suite.Equal(test.column, sourceErr.Column())
+		})
+	}
+}
+
+func (suite *SimpleTestSuite) TestMultipleErrors() {
+	_, _, err := simple.Simple("int a = ;\nreturn a +;", nil)
+	var errs simple.SourceErrors
+	suite.Require().ErrorAs(err, &errs)
+	suite.Require().Len(errs, 2)
+	suite.Equal(8, errs[0].Offset())
+	suite.Equal(20, errs[1].Offset())
+	suite.Contains(err.Error(), "1:9: Syntax error: expected identifier\n")
+	suite.Contains(err.Error(), "2:11: Syntax error: expected identifier\n")
+}
+
+func (suite *SimpleTestSuite) TestRenderError() {
+	subTests := []struct {
+		name   string
+		input  string
+		color  bool
+		output string
+	}{
+		{
+			name:   "SingleLine",
+			input:  "return x;",
+			output: "1:8: Compute error: unknown identifier\n1 | return x;\n  |        ^",
+		},
+		{
+			name:   "Context",
+			input:  "int a = 1;\nint b = 2;\nint c = 3;\nreturn d;\nreturn a;",
+			output: "4:8: Compute error: unknown identifier\n2 | int b = 2;\n3 | int c = 3;\n4 | return d;\n  |        ^",
+		},
+		{
+			name:   "Tabs",
+			input:  "{\n\tint a = 1;\n\treturn\tb;\n}",
+			output: "3:9: Compute error: unknown identifier\n1 | {\n2 | \tint a = 1;\n3 | \treturn\tb;\n  | \t      \t^",
+		},
+		{
+			name:   "WideLineNumbers",
+			input:  "\n\n\n\n\n\n\n\n\nreturn 1\n",
+			output: "10:10: Syntax error: expected ; after expression\n 8 | \n 9 | \n10 | return 1\n   |          ^",
+		},
+		{
+			name:   "CarriageReturn",
+			input:  "int a = 1;\r\nreturn b;\r\n",
+			output: "2:8: Compute error: unknown identifier\n1 | int a = 1;\n2 | return b;\n  |        ^",
+		},
+		{
+			name:   "Color",
+			input:  "return x;",
+			color:  true,
+			output: "\033[1m1:8:\033[0m \033[1m\033[31mCompute error: unknown identifier\033[0m\n1 | return x;\n  |        \033[1m\033[31m^\033[0m",
+		},
+	}
+	for _, test := range subTests {
+		suite.Run(test.name, func() {
+			_, _, err := simple.Simple(test.input, nil)
+			var sourceErr *simple.SourceError
+			suite.Require().ErrorAs(err, &sourceErr)
+			suite.Equal(test.output, sourceErr.Render(test.color))
+		})
+	}
+}
+
 func TestSimple(t *testing.T) {
 	suite.Run(t, new(SimpleTestSuite))
 }
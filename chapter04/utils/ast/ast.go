@@ -2,19 +2,37 @@ package ast
 
 import (
 	"fmt"
-	"go/ast"
 	"go/token"
 	"strconv"
+
+	"github.com/SeaOfNodes/Simple-Go/chapter04/ast"
 )
 
 func Num(i int) *ast.BasicLit {
 	return &ast.BasicLit{Value: strconv.Itoa(i), Kind: token.INT}
 }
 
+// Int creates an int literal, written as in the source.
+func Int(value string) *ast.BasicLit {
+	return &ast.BasicLit{Value: value, Kind: token.INT}
+}
+
+// Flt creates a float literal, written as in the source.
+func Flt(value string) *ast.BasicLit {
+	return &ast.BasicLit{Value: value, Kind: token.FLOAT}
+}
+
+// Str creates a string literal. The value is written as in the source, with quotes and escapes.
+func Str(value string) *ast.BasicLit {
+	return &ast.BasicLit{Value: value, Kind: token.STRING}
+}
+
 func Expr(a any) ast.Expr {
 	switch t := a.(type) {
 	case int:
 		return Num(t)
+	case float64:
+		return Flt(strconv.FormatFloat(t, 'g', -1, 64))
 	case string:
 		return ID(t)
 	case ast.Expr:
@@ -36,18 +54,24 @@ func Paren(value any) *ast.ParenExpr {
 }
 
 func Assign(id string, value any) *ast.AssignStmt {
-	return &ast.AssignStmt{Lhs: []ast.Expr{ID(id)}, Rhs: []ast.Expr{Expr(value)}}
+	return &ast.AssignStmt{Lhs: ID(id), Tok: token.ASSIGN, Rhs: Expr(value)}
+}
+
+// Decl creates a declaration of an int.
+func Decl(id string, value any) *ast.VarDecl {
+	return TypedDecl("int", id, value)
 }
 
-func Decl(id string, value any) *ast.DeclStmt {
-	return &ast.DeclStmt{Decl: &ast.GenDecl{
-		Specs: []ast.Spec{
-			&ast.ValueSpec{
-				Names:  []*ast.Ident{ID(id)},
-				Values: []ast.Expr{Expr(value)},
-			},
-		},
-	}}
+// Var creates a declaration whose type is inferred from the value.
+func Var(id string, value any) *ast.VarDecl {
+	return &ast.VarDecl{Name: ID(id), Value: Expr(value)}
+}
+
+// Val creates a declaration whose type is inferred from the value and which cannot be assigned again.
+func Val(id string, value any) *ast.VarDecl {
+	d := Var(id, value)
+	d.Final = true
+	return d
 }
 
 func Op(op string) token.Token {
@@ -76,6 +100,34 @@ func Op(op string) token.Token {
 		return token.GEQ
 	case "!":
 		return token.NOT
+	case "&&":
+		return token.LAND
+	case "||":
+		return token.LOR
+	case "&":
+		return token.AND
+	case "|":
+		return token.OR
+	case "^":
+		return token.XOR
+	case "<<":
+		return token.SHL
+	case ">>":
+		return token.SHR
+	case ">>>":
+		return ast.LShr
+	case "+=":
+		return token.ADD_ASSIGN
+	case "-=":
+		return token.SUB_ASSIGN
+	case "*=":
+		return token.MUL_ASSIGN
+	case "/=":
+		return token.QUO_ASSIGN
+	case "++":
+		return token.INC
+	case "--":
+		return token.DEC
 	}
 	panic(fmt.Sprintf("unknown op: %s", op))
 }
@@ -85,13 +137,153 @@ func ID(name string) *ast.Ident {
 }
 
 func Ret(expr any) *ast.ReturnStmt {
-	return &ast.ReturnStmt{
-		Results: []ast.Expr{
-			Expr(expr),
-		},
-	}
+	return &ast.ReturnStmt{Result: Expr(expr)}
 }
 
 func Block(stmts ...ast.Stmt) *ast.BlockStmt {
 	return &ast.BlockStmt{List: stmts}
 }
+
+// While creates a loop, which is a for statement with only a condition.
+func While(cond any, body ...ast.Stmt) *ast.ForStmt {
+	return &ast.ForStmt{Cond: Expr(cond), Body: Block(body...)}
+}
+
+// For creates a loop with an init and a post statement, either of which may be nil.
+func For(init ast.Stmt, cond any, post ast.Stmt, body ...ast.Stmt) *ast.ForStmt {
+	return &ast.ForStmt{Init: init, Cond: Expr(cond), Post: post, Body: Block(body...)}
+}
+
+// Switch creates a switch over the tag.
+func Switch(tag any, cases ...*ast.CaseClause) *ast.SwitchStmt {
+	return &ast.SwitchStmt{Tag: Expr(tag), Cases: cases}
+}
+
+// Case creates a case of a switch with the given values.
+func Case(values []any, body ...ast.Stmt) *ast.CaseClause {
+	c := &ast.CaseClause{Body: Block(body...)}
+	for _, v := range values {
+		c.List = append(c.List, Expr(v))
+	}
+	return c
+}
+
+// Default creates the default case of a switch.
+func Default(body ...ast.Stmt) *ast.CaseClause {
+	return &ast.CaseClause{Body: Block(body...)}
+}
+
+// Cond creates the conditional expression cond ? x : y.
+func Cond(cond any, x any, y any) *ast.CondExpr {
+	return &ast.CondExpr{Cond: Expr(cond), X: Expr(x), Y: Expr(y)}
+}
+
+// Func creates a declaration of a function with int parameters that returns int.
+func Func(name string, params []string, body ...ast.Stmt) *ast.FuncDecl {
+	var fields []*ast.Field
+	for _, param := range params {
+		fields = append(fields, &ast.Field{Type: ID("int"), Name: ID(param)})
+	}
+	return &ast.FuncDecl{Result: ID("int"), Name: ID(name), Params: fields, Body: Block(body...)}
+}
+
+func Call(name string, args ...any) *ast.CallExpr {
+	call := &ast.CallExpr{Fun: ID(name)}
+	for _, arg := range args {
+		call.Args = append(call.Args, Expr(arg))
+	}
+	return call
+}
+
+func Break() *ast.BranchStmt {
+	return &ast.BranchStmt{Tok: token.BREAK}
+}
+
+func Continue() *ast.BranchStmt {
+	return &ast.BranchStmt{Tok: token.CONTINUE}
+}
+
+// If creates an if statement. els may be nil when there is no else branch.
+func If(cond any, body ast.Stmt, els ast.Stmt) *ast.IfStmt {
+	b, ok := body.(*ast.BlockStmt)
+	if !ok {
+		b = Block(body)
+	}
+	return &ast.IfStmt{Cond: Expr(cond), Body: b, Else: els}
+}
+
+// Struct creates a declaration of a struct with int fields.
+func Struct(name string, fields ...string) *ast.StructDecl {
+	var list []*ast.Field
+	for _, field := range fields {
+		list = append(list, &ast.Field{Type: ID("int"), Name: ID(field)})
+	}
+	return &ast.StructDecl{Name: ID(name), Fields: list}
+}
+
+// TypedDecl creates a declaration with a type name.
+func TypedDecl(typ string, id string, value any) *ast.VarDecl {
+	d := Var(id, value)
+	d.Type = ID(typ)
+	return d
+}
+
+// New creates an allocation of a struct.
+func New(name string) *ast.NewExpr {
+	return &ast.NewExpr{Type: ID(name)}
+}
+
+func Field(x any, name string) *ast.FieldExpr {
+	return &ast.FieldExpr{X: Expr(x), Field: ID(name)}
+}
+
+// AssignOp creates a compound assignment such as x += value.
+func AssignOp(id string, op string, value any) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: ID(id), Tok: Op(op), Rhs: Expr(value)}
+}
+
+// IncDec creates x++ or x--.
+func IncDec(id string, op string) *ast.IncDecStmt {
+	return &ast.IncDecStmt{X: ID(id), Tok: Op(op)}
+}
+
+func Store(x any, name string, value any) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: Field(x, name), Tok: token.ASSIGN, Rhs: Expr(value)}
+}
+
+// IntArray is the type of int arrays.
+func IntArray() *ast.ArrayType {
+	return &ast.ArrayType{Elt: ID("int")}
+}
+
+// NewArray creates an allocation of an int array with the given length.
+func NewArray(length any) *ast.NewExpr {
+	return &ast.NewExpr{Type: ID("int"), Len: Expr(length)}
+}
+
+// ArrayDecl creates a declaration of an int array.
+func ArrayDecl(id string, value any) *ast.VarDecl {
+	d := Var(id, value)
+	d.Type = IntArray()
+	return d
+}
+
+func Index(x any, index any) *ast.IndexExpr {
+	return &ast.IndexExpr{X: Expr(x), Index: Expr(index)}
+}
+
+// Len creates the length of an array.
+func Len(x any) *ast.LenExpr {
+	return &ast.LenExpr{X: Expr(x)}
+}
+
+func StoreIndex(x any, index any, value any) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: Index(x, index), Tok: token.ASSIGN, Rhs: Expr(value)}
+}
+
+// NullableDecl creates a declaration of a nullable reference to a struct.
+func NullableDecl(typ string, id string, value any) *ast.VarDecl {
+	d := Var(id, value)
+	d.Type = &ast.NullableType{X: ID(typ)}
+	return d
+}